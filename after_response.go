@@ -0,0 +1,14 @@
+package mcp_golang
+
+import (
+	"context"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+)
+
+// NotifyAfterResponse schedules a notification, e.g. that an operation completed, to be sent to the client right after
+// the response to the request a handler is serving. ctx must be the context passed to the handler.
+// On request/response transports such as HTTP the notification is sent in a batch with the response.
+func NotifyAfterResponse(ctx context.Context, method string, params any) error {
+	return protocol.NotifyAfterResponse(ctx, method, params)
+}
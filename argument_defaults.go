@@ -0,0 +1,69 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/invopop/jsonschema"
+)
+
+// applyArgumentDefaults sets the schema's default value on every property missing from the arguments, including properties
+// of nested objects that are present. Arguments that are not a JSON object, e.g. ones for a custom Decoder, are returned unchanged.
+func applyArgumentDefaults(schema *jsonschema.Schema, arguments json.RawMessage) json.RawMessage {
+	trimmed := bytes.TrimSpace(arguments)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		trimmed = []byte("{}")
+	}
+	if trimmed[0] != '{' || !hasDefaults(schema) {
+		return arguments
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return arguments
+	}
+	if !setDefaults(schema, object) {
+		return arguments
+	}
+	withDefaults, err := json.Marshal(object)
+	if err != nil {
+		return arguments
+	}
+	return withDefaults
+}
+
+// setDefaults sets the defaults of the schema's properties that are missing from object, and reports whether any were set
+func setDefaults(schema *jsonschema.Schema, object map[string]interface{}) bool {
+	if schema == nil || schema.Properties == nil {
+		return false
+	}
+	changed := false
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		value, ok := object[pair.Key]
+		if !ok {
+			if pair.Value.Default != nil {
+				object[pair.Key] = pair.Value.Default
+				changed = true
+			}
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok && setDefaults(pair.Value, nested) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// hasDefaults reports whether the schema or any of its nested object properties have a default
+func hasDefaults(schema *jsonschema.Schema) bool {
+	if schema == nil || schema.Properties == nil {
+		return false
+	}
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		if pair.Value.Default != nil || hasDefaults(pair.Value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+)
+
+// argumentValidationError describes the first argument that doesn't match a tool's input schema.
+// Pointer is a JSON pointer (RFC 6901) to the offending value, e.g. /content/title.
+type argumentValidationError struct {
+	Pointer string
+	Message string
+}
+
+func (e *argumentValidationError) Error() string {
+	if e.Pointer == "" {
+		return fmt.Sprintf("invalid arguments: %s", e.Message)
+	}
+	return fmt.Sprintf("invalid argument %s: %s", e.Pointer, e.Message)
+}
+
+// validateArguments checks the arguments of a tool call against the input schema generated for its handler.
+// It covers the constraints that can be set with jsonschema struct tags: required, type, enum, length, pattern and range.
+func validateArguments(schema *jsonschema.Schema, arguments json.RawMessage) *argumentValidationError {
+	if len(bytes.TrimSpace(arguments)) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(arguments))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return &argumentValidationError{Message: err.Error()}
+	}
+	if value == nil {
+		value = map[string]interface{}{}
+	}
+	return validateValue(schema, value, "")
+}
+
+func validateValue(schema *jsonschema.Schema, value interface{}, pointer string) *argumentValidationError {
+	if schema == nil {
+		return nil
+	}
+	invalid := func(format string, args ...interface{}) *argumentValidationError {
+		return &argumentValidationError{Pointer: pointer, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalid("must be one of %v", schema.Enum)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid("must be an object")
+		}
+		for _, name := range schema.Required {
+			if v, ok := object[name]; !ok || v == nil {
+				return &argumentValidationError{Pointer: pointer + "/" + escapeJSONPointer(name), Message: "is required"}
+			}
+		}
+		if schema.Properties != nil {
+			for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+				v, ok := object[pair.Key]
+				if !ok || v == nil {
+					continue
+				}
+				if err := validateValue(pair.Value, v, pointer+"/"+escapeJSONPointer(pair.Key)); err != nil {
+					return err
+				}
+			}
+		}
+		// Maps are described by additionalProperties rather than properties
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Type != "" {
+			for key, v := range object {
+				if schema.Properties != nil {
+					if _, ok := schema.Properties.Get(key); ok {
+						continue
+					}
+				}
+				if v == nil {
+					continue
+				}
+				if err := validateValue(schema.AdditionalProperties, v, pointer+"/"+escapeJSONPointer(key)); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return invalid("must be an array")
+		}
+		if schema.MinItems != nil && uint64(len(array)) < *schema.MinItems {
+			return invalid("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && uint64(len(array)) > *schema.MaxItems {
+			return invalid("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range array {
+			if err := validateValue(schema.Items, item, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		length := uint64(utf8.RuneCountInString(s))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return invalid("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return invalid("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			matched, err := regexp.MatchString(schema.Pattern, s)
+			if err == nil && !matched {
+				return invalid("must match the pattern %s", schema.Pattern)
+			}
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return invalid("must be a %s", schema.Type)
+		}
+		f, err := number.Float64()
+		if err != nil {
+			return invalid("must be a %s", schema.Type)
+		}
+		if schema.Type == "integer" && f != math.Trunc(f) {
+			return invalid("must be an integer")
+		}
+		if limit, err := schema.Minimum.Float64(); schema.Minimum != "" && err == nil && f < limit {
+			return invalid("must be at least %s", schema.Minimum)
+		}
+		if limit, err := schema.Maximum.Float64(); schema.Maximum != "" && err == nil && f > limit {
+			return invalid("must be at most %s", schema.Maximum)
+		}
+		if limit, err := schema.ExclusiveMinimum.Float64(); schema.ExclusiveMinimum != "" && err == nil && f <= limit {
+			return invalid("must be greater than %s", schema.ExclusiveMinimum)
+		}
+		if limit, err := schema.ExclusiveMaximum.Float64(); schema.ExclusiveMaximum != "" && err == nil && f >= limit {
+			return invalid("must be less than %s", schema.ExclusiveMaximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("must be a boolean")
+		}
+	}
+	return nil
+}
+
+// escapeJSONPointer escapes a reference token as described in RFC 6901
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
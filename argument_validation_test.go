@@ -0,0 +1,204 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationContent struct {
+	Title string   `json:"title" jsonschema:"required,maxLength=10"`
+	Tags  []string `json:"tags" jsonschema:"maxItems=2"`
+}
+
+type validationArgs struct {
+	Content  validationContent `json:"content" jsonschema:"required"`
+	Priority int               `json:"priority" jsonschema:"minimum=1,maximum=5"`
+	Mode     string            `json:"mode" jsonschema:"enum=draft,enum=publish"`
+}
+
+func TestValidateArguments(t *testing.T) {
+	schema := jsonSchemaReflector.Reflect(validationArgs{})
+
+	tests := []struct {
+		name            string
+		arguments       string
+		expectedPointer string
+	}{
+		{name: "valid", arguments: `{"content":{"title":"Hello","tags":["a"]},"priority":3,"mode":"draft"}`},
+		{name: "missing nested required field", arguments: `{"content":{}}`, expectedPointer: "/content/title"},
+		{name: "nested constraint", arguments: `{"content":{"title":"This title is too long"}}`, expectedPointer: "/content/title"},
+		{name: "array item count", arguments: `{"content":{"title":"Hi","tags":["a","b","c"]}}`, expectedPointer: "/content/tags"},
+		{name: "wrong type in array", arguments: `{"content":{"title":"Hi","tags":["a",1]}}`, expectedPointer: "/content/tags/1"},
+		{name: "missing top level field", arguments: `{}`, expectedPointer: "/content"},
+		{name: "out of range", arguments: `{"content":{"title":"Hi"},"priority":9}`, expectedPointer: "/priority"},
+		{name: "not an integer", arguments: `{"content":{"title":"Hi"},"priority":1.5}`, expectedPointer: "/priority"},
+		{name: "not in enum", arguments: `{"content":{"title":"Hi"},"mode":"delete"}`, expectedPointer: "/mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArguments(schema, json.RawMessage(tt.arguments))
+			if tt.expectedPointer == "" {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Equal(t, tt.expectedPointer, err.Pointer)
+		})
+	}
+
+	assert.Equal(t, "a~1b~0c", escapeJSONPointer("a/b~c"))
+}
+
+func TestToolCallValidationError(t *testing.T) {
+	server := NewServer(nil)
+	handlerCalled := false
+	err := server.RegisterTool("publish", "Publish content", func(arguments validationArgs) (*ToolResponse, error) {
+		handlerCalled = true
+		return NewToolResponse(NewTextContent("published")), nil
+	})
+	require.NoError(t, err)
+
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"publish","arguments":{"content":{"tags":[]}}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.Error(t, err)
+	assert.False(t, handlerCalled)
+
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+	assert.Equal(t, map[string]string{"pointer": "/content/title"}, rpcErr.Data)
+}
+
+func TestToolCallSchemaDefaults(t *testing.T) {
+	type forecastOptions struct {
+		Days int `json:"days" jsonschema:"default=3"`
+	}
+	type forecastArgs struct {
+		City    string          `json:"city" jsonschema:"required"`
+		Units   string          `json:"units" jsonschema:"required,enum=metric,enum=imperial,default=metric"`
+		Options forecastOptions `json:"options"`
+	}
+	server := NewServer(nil)
+	var received forecastArgs
+	err := server.RegisterTool("forecast", "Get the forecast", func(arguments forecastArgs) (*ToolResponse, error) {
+		received = arguments
+		return NewToolResponse(NewTextContent("sunny")), nil
+	})
+	require.NoError(t, err)
+
+	// The required units argument is left out, its default is applied before validation
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"forecast","arguments":{"city":"London","options":{}}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Equal(t, forecastArgs{City: "London", Units: "metric", Options: forecastOptions{Days: 3}}, received)
+
+	// Arguments that are sent are kept
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"forecast","arguments":{"city":"Boston","units":"imperial","options":{"days":7}}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Equal(t, forecastArgs{City: "Boston", Units: "imperial", Options: forecastOptions{Days: 7}}, received)
+
+	// Defaults of a custom schema reach the raw arguments
+	var rawArguments json.RawMessage
+	err = server.RegisterToolFromSchema("search", "Search", json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"},"limit":{"type":"integer","default":10}}}`), func(args json.RawMessage) (*ToolResponse, error) {
+		rawArguments = args
+		return NewToolResponse(NewTextContent("results")), nil
+	})
+	require.NoError(t, err)
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"search","arguments":{"query":"mcp"}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":"mcp","limit":10}`, string(rawArguments))
+}
+
+func TestToolMaxArgBytes(t *testing.T) {
+	type args struct {
+		Text string `json:"text"`
+	}
+	server := NewServer(nil, WithToolMaxArgBytes("summarize", 32))
+	calls := 0
+	handler := func(arguments args) (*ToolResponse, error) {
+		calls++
+		return NewToolResponse(NewTextContent(arguments.Text)), nil
+	}
+	require.NoError(t, server.RegisterTool("summarize", "Summarize text", handler))
+	require.NoError(t, server.RegisterTool("echo", "Echo text", handler))
+
+	call := func(name, text string) error {
+		arguments, err := json.Marshal(args{Text: text})
+		require.NoError(t, err)
+		params, err := json.Marshal(map[string]any{"name": name, "arguments": json.RawMessage(arguments)})
+		require.NoError(t, err)
+		_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{Params: params}, protocol.RequestHandlerExtra{})
+		return err
+	}
+
+	err := call("summarize", strings.Repeat("a", 100))
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "more than the limit of 32")
+	assert.Equal(t, 0, calls)
+
+	// Arguments within the limit, and tools without one, are handled as usual
+	assert.NoError(t, call("summarize", "short"))
+	assert.NoError(t, call("echo", strings.Repeat("a", 100)))
+	assert.Equal(t, 2, calls)
+}
+
+func TestToolCallArgumentsDecodeError(t *testing.T) {
+	type args struct {
+		Count int8 `json:"count"`
+	}
+	passThrough := func(next ToolHandlerFunc) ToolHandlerFunc {
+		return next
+	}
+	for name, server := range map[string]*Server{
+		"without middleware": NewServer(nil),
+		// Middleware must not turn the decode error into a tool error
+		"with middleware": NewServer(nil, WithGlobalToolMiddleware(passThrough)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			handlerCalled := false
+			err := server.RegisterTool("count", "Counts", func(arguments args) (*ToolResponse, error) {
+				handlerCalled = true
+				return NewToolResponse(NewTextContent("counted")), nil
+			})
+			require.NoError(t, err)
+
+			call := func(arguments string) *RPCError {
+				_, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+					Params: []byte(`{"name":"count","arguments":` + arguments + `}`),
+				}, protocol.RequestHandlerExtra{})
+				var rpcErr *RPCError
+				require.True(t, errors.As(err, &rpcErr), "expected an RPC error, got %v", err)
+				return rpcErr
+			}
+
+			// The value is an integer as the schema requires, but doesn't fit the field
+			rpcErr := call(`{"count":1000}`)
+			assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+			data, ok := rpcErr.Data.(map[string]string)
+			require.True(t, ok)
+			assert.Equal(t, "count", data["field"])
+			assert.Contains(t, data["detail"], "int8")
+
+			rpcErr = call(`{"count":"many"}`)
+			assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+			assert.False(t, handlerCalled)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package mcp_golang
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// charsetDecoders convert text in a charset other than UTF-8 to UTF-8, replacing invalid sequences with utf8.RuneError.
+// They are keyed by the lower case charset names of the charset parameter of a mime type.
+var charsetDecoders = map[string]func(text string) string{
+	"us-ascii":   decodeASCII,
+	"ascii":      decodeASCII,
+	"iso-8859-1": decodeLatin1,
+	"iso_8859-1": decodeLatin1,
+	"latin1":     decodeLatin1,
+	"l1":         decodeLatin1,
+	"utf-16":     decodeUTF16,
+	"utf-16be":   func(text string) string { return decodeUTF16Endian(text, true) },
+	"utf-16le":   func(text string) string { return decodeUTF16Endian(text, false) },
+}
+
+// transcodeText converts text in the charset of mimeType, e.g. "text/plain; charset=iso-8859-1", to UTF-8, which JSON requires.
+// Invalid sequences are replaced with utf8.RuneError, and the returned mime type has its charset changed to utf-8.
+// Text in a charset without a decoder, e.g. windows-1252, keeps its mime type and only has invalid UTF-8 replaced.
+// Text without a charset is assumed to be UTF-8 and returned as is.
+func transcodeText(text string, mimeType string) (string, string) {
+	mediaType, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return text, mimeType
+	}
+	charset, ok := params["charset"]
+	if !ok {
+		return text, mimeType
+	}
+	decode, ok := charsetDecoders[strings.ToLower(charset)]
+	if !ok {
+		return decodeUTF8(text), mimeType
+	}
+	params["charset"] = "utf-8"
+	return decode(text), mime.FormatMediaType(mediaType, params)
+}
+
+// transcodeEmbeddedResource returns resource with its text transcoded to UTF-8 according to its mime type,
+// copied if that changed it. It is applied to what the server sends only, as resources a client decoded are UTF-8 already.
+func transcodeEmbeddedResource(resource *EmbeddedResource) *EmbeddedResource {
+	if resource == nil || resource.EmbeddedResourceType != embeddedResourceTypeText || resource.TextResourceContents == nil || resource.TextResourceContents.MimeType == nil {
+		return resource
+	}
+	contents := *resource.TextResourceContents
+	text, mimeType := transcodeText(contents.Text, *contents.MimeType)
+	if text == contents.Text && mimeType == *contents.MimeType {
+		return resource
+	}
+	contents.Text, contents.MimeType = text, &mimeType
+	transcoded := *resource
+	transcoded.TextResourceContents = &contents
+	return &transcoded
+}
+
+// transcodeEmbeddedResources applies transcodeEmbeddedResource to resources, copying the slice if any of them changed
+func transcodeEmbeddedResources(resources []*EmbeddedResource) []*EmbeddedResource {
+	var transcoded []*EmbeddedResource
+	for i, resource := range resources {
+		if r := transcodeEmbeddedResource(resource); r != resource {
+			if transcoded == nil {
+				transcoded = append([]*EmbeddedResource(nil), resources...)
+			}
+			transcoded[i] = r
+		}
+	}
+	if transcoded == nil {
+		return resources
+	}
+	return transcoded
+}
+
+// transcodeContent applies transcodeEmbeddedResource to the resource of embedded resource content, copying it if it changed
+func transcodeContent(content *Content) *Content {
+	if content == nil || content.Type != ContentTypeEmbeddedResource {
+		return content
+	}
+	resource := transcodeEmbeddedResource(content.EmbeddedResource)
+	if resource == content.EmbeddedResource {
+		return content
+	}
+	transcoded := *content
+	transcoded.EmbeddedResource = resource
+	return &transcoded
+}
+
+// transcodeContents applies transcodeContent to contents, copying the slice if any of them changed
+func transcodeContents(contents []*Content) []*Content {
+	var transcoded []*Content
+	for i, content := range contents {
+		if c := transcodeContent(content); c != content {
+			if transcoded == nil {
+				transcoded = append([]*Content(nil), contents...)
+			}
+			transcoded[i] = c
+		}
+	}
+	if transcoded == nil {
+		return contents
+	}
+	return transcoded
+}
+
+func decodeUTF8(text string) string {
+	return strings.ToValidUTF8(text, string(utf8.RuneError))
+}
+
+func decodeASCII(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		if text[i] < utf8.RuneSelf {
+			b.WriteByte(text[i])
+		} else {
+			b.WriteRune(utf8.RuneError)
+		}
+	}
+	return b.String()
+}
+
+func decodeLatin1(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		b.WriteRune(rune(text[i]))
+	}
+	return b.String()
+}
+
+// decodeUTF16 decodes UTF-16 with an optional byte order mark, big endian without one as RFC 2781 specifies
+func decodeUTF16(text string) string {
+	switch {
+	case strings.HasPrefix(text, "\xfe\xff"):
+		return decodeUTF16Endian(text[2:], true)
+	case strings.HasPrefix(text, "\xff\xfe"):
+		return decodeUTF16Endian(text[2:], false)
+	default:
+		return decodeUTF16Endian(text, true)
+	}
+}
+
+func decodeUTF16Endian(text string, bigEndian bool) string {
+	units := make([]uint16, 0, len(text)/2)
+	for i := 0; i+1 < len(text); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(text[i])<<8|uint16(text[i+1]))
+		} else {
+			units = append(units, uint16(text[i+1])<<8|uint16(text[i]))
+		}
+	}
+	decoded := string(utf16.Decode(units))
+	if len(text)%2 != 0 {
+		decoded += string(utf8.RuneError)
+	}
+	return decoded
+}
@@ -1,29 +1,86 @@
 package mcp_golang
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"io"
+	"time"
 
 	"github.com/metoro-io/mcp-golang/internal/protocol"
 	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/pkg/errors"
 )
 
+// DefaultInitializeTimeout is how long Initialize waits for the server to answer the initialize request
+const DefaultInitializeTimeout = 30 * time.Second
+
 // Client represents an MCP client that can connect to and interact with MCP servers
 type Client struct {
-	transport    transport.Transport
-	protocol     *protocol.Protocol
-	capabilities *ServerCapabilities
-	initialized  bool
-	info         ClientInfo
+	transport         transport.Transport
+	protocol          *protocol.Protocol
+	capabilities      *ServerCapabilities
+	rawCapabilities   json.RawMessage
+	protocolVersion   string
+	initialized       bool
+	info              ClientInfo
+	initializeTimeout time.Duration
+	// Parent of the client's lifecycle, the client is closed when it is cancelled
+	ctx context.Context
+	// Set by SetElicitationHandler, advertised as the elicitation capability
+	elicitationEnabled bool
+	// Set by WithClientInterceptor, run around every request
+	interceptors []ClientInterceptor
+}
+
+type ClientOptions func(*Client)
+
+// WithInitializeTimeout sets how long Initialize waits for the server to respond before giving up
+func WithInitializeTimeout(timeout time.Duration) ClientOptions {
+	return func(c *Client) {
+		c.initializeTimeout = timeout
+	}
+}
+
+// WithContext binds the client to ctx. The transport is started with ctx, and the client is closed once ctx is cancelled,
+// e.g. when the application shuts down.
+func WithContext(ctx context.Context) ClientOptions {
+	return func(c *Client) {
+		c.ctx = ctx
+	}
+}
+
+// WithNotificationHandler calls handler with the params of every notification the server sends for method.
+// An error returned by handler is passed to the handler set with WithErrorHandler.
+func WithNotificationHandler(method string, handler func(params json.RawMessage) error) ClientOptions {
+	return func(c *Client) {
+		c.protocol.SetNotificationHandler(method, func(notification *transport.BaseJSONRPCNotification) error {
+			return handler(notification.Params)
+		})
+	}
+}
+
+// WithErrorHandler sets a callback for errors that can't be returned to a caller, such as transport errors
+// and errors returned by notification handlers. They are dropped if it is not set.
+func WithErrorHandler(handler func(error)) ClientOptions {
+	return func(c *Client) {
+		c.protocol.OnError = handler
+	}
 }
 
 // NewClient creates a new MCP client with the specified transport
-func NewClient(transport transport.Transport) *Client {
-	return &Client{
-		transport: transport,
-		protocol:  protocol.NewProtocol(nil),
+func NewClient(transport transport.Transport, options ...ClientOptions) *Client {
+	client := &Client{
+		transport:         transport,
+		protocol:          protocol.NewProtocol(nil),
+		initializeTimeout: DefaultInitializeTimeout,
+		ctx:               context.Background(),
+	}
+	for _, option := range options {
+		option(client)
 	}
+	return client
 }
 
 type ClientInfo struct {
@@ -32,47 +89,65 @@ type ClientInfo struct {
 }
 
 // NewClientWithInfo create a new client with info. This is required by anthorpic mcp tools
-func NewClientWithInfo(transport transport.Transport, info ClientInfo) *Client {
-	return &Client{
-		transport: transport,
-		protocol:  protocol.NewProtocol(nil),
-		info:      info,
-	}
+func NewClientWithInfo(transport transport.Transport, info ClientInfo, options ...ClientOptions) *Client {
+	client := NewClient(transport, options...)
+	client.info = info
+	return client
 }
 
 // Initialize connects to the server and retrieves its capabilities
 func (c *Client) Initialize(ctx context.Context) (*InitializeResponse, error) {
 	if c.initialized {
-		return nil, errors.New("client already initialized")
+		return nil, ErrClientAlreadyInitialized
 	}
 
-	err := c.protocol.Connect(c.transport)
+	err := c.protocol.ConnectWithContext(c.ctx, c.transport)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to connect transport")
 	}
+	context.AfterFunc(c.ctx, func() {
+		c.Close()
+	})
 
 	// Make initialize request to server
-	response, err := c.protocol.Request(ctx, "initialize", map[string]interface{}{
+	start := time.Now()
+	var rawResult json.RawMessage
+	capabilities := map[string]interface{}{}
+	if c.elicitationEnabled {
+		capabilities["elicitation"] = map[string]interface{}{}
+	}
+	err = c.request(ctx, "initialize", map[string]interface{}{
 		"protocolVersion": "1.0",
-		"capabilities":    map[string]interface{}{},
+		"capabilities":    capabilities,
 		"clientInfo":      c.info,
-	}, nil)
+	}, &rawResult, &protocol.RequestOptions{
+		Timeout: c.initializeTimeout,
+	})
+	var rpcErr *protocol.RPCError
+	timedOut := errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		(errors.As(err, &rpcErr) && rpcErr.Code == protocol.ErrorCodeRequestTimeout)
+	if timedOut {
+		return nil, errors.Wrapf(err, "failed to initialize, no valid response from the server after %s", time.Since(start).Round(time.Millisecond))
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to initialize")
 	}
 
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
-	}
-
 	var initResult InitializeResponse
-	err = json.Unmarshal(responseBytes, &initResult)
-	if err != nil {
+	if err := json.Unmarshal(rawResult, &initResult); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal initialize response")
+	}
+	// Keep the capabilities as sent by the server, as the typed struct drops fields it doesn't model
+	var rawCapabilities struct {
+		Capabilities json.RawMessage `json:"capabilities"`
+	}
+	if err := json.Unmarshal(rawResult, &rawCapabilities); err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal initialize response")
 	}
 
 	c.capabilities = &initResult.Capabilities
+	c.rawCapabilities = rawCapabilities.Capabilities
+	c.protocolVersion = initResult.ProtocolVersion
 	c.initialized = true
 	return &initResult, nil
 }
@@ -80,27 +155,20 @@ func (c *Client) Initialize(ctx context.Context) (*InitializeResponse, error) {
 // ListTools retrieves the list of available tools from the server
 func (c *Client) ListTools(ctx context.Context, cursor *string) (*ToolsResponse, error) {
 	if !c.initialized {
-		return nil, errors.New("client not initialized")
+		return nil, ErrClientNotInitialized
+	}
+	if c.capabilities.Tools == nil {
+		return nil, ErrToolsNotSupported
 	}
 
 	params := map[string]interface{}{
 		"cursor": cursor,
 	}
 
-	response, err := c.protocol.Request(ctx, "tools/list", params, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to list tools")
-	}
-
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
-	}
-
 	var toolsResponse ToolsResponse
-	err = json.Unmarshal(responseBytes, &toolsResponse)
+	err := c.request(ctx, "tools/list", params, &toolsResponse, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal tools response")
+		return nil, errors.Wrap(err, "failed to list tools")
 	}
 
 	return &toolsResponse, nil
@@ -109,7 +177,10 @@ func (c *Client) ListTools(ctx context.Context, cursor *string) (*ToolsResponse,
 // CallTool calls a specific tool on the server with the provided arguments
 func (c *Client) CallTool(ctx context.Context, name string, arguments any) (*ToolResponse, error) {
 	if !c.initialized {
-		return nil, errors.New("client not initialized")
+		return nil, ErrClientNotInitialized
+	}
+	if c.capabilities.Tools == nil {
+		return nil, ErrToolsNotSupported
 	}
 
 	argumentsJson, err := json.Marshal(arguments)
@@ -122,20 +193,10 @@ func (c *Client) CallTool(ctx context.Context, name string, arguments any) (*Too
 		Arguments: argumentsJson,
 	}
 
-	response, err := c.protocol.Request(ctx, "tools/call", params, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to call tool")
-	}
-
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
-	}
-
 	var toolResponse ToolResponse
-	err = json.Unmarshal(responseBytes, &toolResponse)
+	err = c.request(ctx, "tools/call", params, &toolResponse, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal tool response")
+		return nil, errors.Wrap(err, "failed to call tool")
 	}
 
 	return &toolResponse, nil
@@ -144,27 +205,20 @@ func (c *Client) CallTool(ctx context.Context, name string, arguments any) (*Too
 // ListPrompts retrieves the list of available prompts from the server
 func (c *Client) ListPrompts(ctx context.Context, cursor *string) (*ListPromptsResponse, error) {
 	if !c.initialized {
-		return nil, errors.New("client not initialized")
+		return nil, ErrClientNotInitialized
+	}
+	if c.capabilities.Prompts == nil {
+		return nil, ErrPromptsNotSupported
 	}
 
 	params := map[string]interface{}{
 		"cursor": cursor,
 	}
 
-	response, err := c.protocol.Request(ctx, "prompts/list", params, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to list prompts")
-	}
-
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
-	}
-
 	var promptsResponse ListPromptsResponse
-	err = json.Unmarshal(responseBytes, &promptsResponse)
+	err := c.request(ctx, "prompts/list", params, &promptsResponse, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal prompts response")
+		return nil, errors.Wrap(err, "failed to list prompts")
 	}
 
 	return &promptsResponse, nil
@@ -173,7 +227,10 @@ func (c *Client) ListPrompts(ctx context.Context, cursor *string) (*ListPromptsR
 // GetPrompt retrieves a specific prompt from the server
 func (c *Client) GetPrompt(ctx context.Context, name string, arguments any) (*PromptResponse, error) {
 	if !c.initialized {
-		return nil, errors.New("client not initialized")
+		return nil, ErrClientNotInitialized
+	}
+	if c.capabilities.Prompts == nil {
+		return nil, ErrPromptsNotSupported
 	}
 
 	argumentsJson, err := json.Marshal(arguments)
@@ -186,20 +243,10 @@ func (c *Client) GetPrompt(ctx context.Context, name string, arguments any) (*Pr
 		Arguments: argumentsJson,
 	}
 
-	response, err := c.protocol.Request(ctx, "prompts/get", params, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get prompt")
-	}
-
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
-	}
-
 	var promptResponse PromptResponse
-	err = json.Unmarshal(responseBytes, &promptResponse)
+	err = c.request(ctx, "prompts/get", params, &promptResponse, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal prompt response")
+		return nil, errors.Wrap(err, "failed to get prompt")
 	}
 
 	return &promptResponse, nil
@@ -207,28 +254,33 @@ func (c *Client) GetPrompt(ctx context.Context, name string, arguments any) (*Pr
 
 // ListResources retrieves the list of available resources from the server
 func (c *Client) ListResources(ctx context.Context, cursor *string) (*ListResourcesResponse, error) {
+	return c.ListResourcesWithFilter(ctx, cursor, ResourceListFilter{})
+}
+
+// ListResourcesWithFilter retrieves the list of available resources from the server, filtered server-side
+// by scheme and/or URI prefix. Pagination applies to the filtered set.
+func (c *Client) ListResourcesWithFilter(ctx context.Context, cursor *string, filter ResourceListFilter) (*ListResourcesResponse, error) {
 	if !c.initialized {
-		return nil, errors.New("client not initialized")
+		return nil, ErrClientNotInitialized
+	}
+	if c.capabilities.Resources == nil {
+		return nil, ErrResourcesNotSupported
 	}
 
 	params := map[string]interface{}{
 		"cursor": cursor,
 	}
-
-	response, err := c.protocol.Request(ctx, "resources/list", params, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to list resources")
+	if filter.Scheme != nil {
+		params["scheme"] = *filter.Scheme
 	}
-
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
+	if filter.Prefix != nil {
+		params["prefix"] = *filter.Prefix
 	}
 
 	var resourcesResponse ListResourcesResponse
-	err = json.Unmarshal(responseBytes, &resourcesResponse)
+	err := c.request(ctx, "resources/list", params, &resourcesResponse, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal resources response")
+		return nil, errors.Wrap(err, "failed to list resources")
 	}
 
 	return &resourcesResponse, nil
@@ -236,40 +288,134 @@ func (c *Client) ListResources(ctx context.Context, cursor *string) (*ListResour
 
 // ReadResource reads a specific resource from the server
 func (c *Client) ReadResource(ctx context.Context, uri string) (*ResourceResponse, error) {
+	return c.readResource(ctx, readResourceRequestParams{
+		Uri: uri,
+	})
+}
+
+// ReadResourceIfModified reads a specific resource from the server unless its etag matches the given etag.
+// If the resource is unchanged, the returned response has NotModified set and no contents.
+func (c *Client) ReadResourceIfModified(ctx context.Context, uri string, etag string) (*ResourceResponse, error) {
+	return c.readResource(ctx, readResourceRequestParams{
+		Uri:         uri,
+		IfNoneMatch: &etag,
+	})
+}
+
+// ReadResourceRange reads the bytes from start up to but not including end of a specific resource.
+// Servers whose handler doesn't support ranges return the full contents, in which case the response's Range is nil.
+func (c *Client) ReadResourceRange(ctx context.Context, uri string, start int64, end int64) (*ResourceResponse, error) {
+	return c.readResource(ctx, readResourceRequestParams{
+		Uri:   uri,
+		Range: &ResourceRange{Start: start, End: end},
+	})
+}
+
+func (c *Client) readResource(ctx context.Context, params readResourceRequestParams) (*ResourceResponse, error) {
+	if !c.initialized {
+		return nil, ErrClientNotInitialized
+	}
+	if c.capabilities.Resources == nil {
+		return nil, ErrResourcesNotSupported
+	}
+
+	var resourceResponse ResourceResponse
+	err := c.request(ctx, "resources/read", params, &resourceResponse, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read resource")
+	}
+
+	return &resourceResponse, nil
+}
+
+// ReadResourceBlob reads a binary resource from the server and returns its decoded bytes along with its MIME type.
+// The blob is decoded straight from the buffered response, without the encoded string that decoding into
+// BlobResourceContents builds, so the response and the decoded bytes are held at the same time.
+// Use ReadResourceBlobReader to consume a large blob without also holding all of its decoded bytes.
+func (c *Client) ReadResourceBlob(ctx context.Context, uri string) ([]byte, string, error) {
+	encoded, mimeType, err := c.readResourceBlob(ctx, uri)
+	if err != nil {
+		return nil, "", err
+	}
+	buffer := bytes.NewBuffer(make([]byte, 0, base64.StdEncoding.DecodedLen(len(encoded))))
+	if _, err := io.Copy(buffer, base64.NewDecoder(base64.StdEncoding, bytes.NewReader(encoded))); err != nil {
+		return nil, "", errors.Wrap(err, "failed to decode blob")
+	}
+	return buffer.Bytes(), mimeType, nil
+}
+
+// ReadResourceBlobReader reads a binary resource from the server and returns a reader of its decoded bytes along with its MIME type.
+// The blob is decoded as the reader is read, so only the buffered response is held in memory. Decoding errors are returned by Read.
+func (c *Client) ReadResourceBlobReader(ctx context.Context, uri string) (io.Reader, string, error) {
+	encoded, mimeType, err := c.readResourceBlob(ctx, uri)
+	if err != nil {
+		return nil, "", err
+	}
+	return base64.NewDecoder(base64.StdEncoding, bytes.NewReader(encoded)), mimeType, nil
+}
+
+// readResourceBlob reads a binary resource and returns its base64 encoded blob, sliced out of the response
+func (c *Client) readResourceBlob(ctx context.Context, uri string) ([]byte, string, error) {
 	if !c.initialized {
-		return nil, errors.New("client not initialized")
+		return nil, "", ErrClientNotInitialized
+	}
+	if c.capabilities.Resources == nil {
+		return nil, "", ErrResourcesNotSupported
 	}
 
 	params := readResourceRequestParams{
 		Uri: uri,
 	}
 
-	response, err := c.protocol.Request(ctx, "resources/read", params, nil)
+	// Only the blob of the first content is needed, so keep it as raw JSON rather than decoding it into a string
+	var resourceResponse struct {
+		Contents []struct {
+			Blob     json.RawMessage `json:"blob"`
+			MimeType *string         `json:"mimeType"`
+		} `json:"contents"`
+	}
+	err := c.request(ctx, "resources/read", params, &resourceResponse, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to read resource")
+		return nil, "", errors.Wrap(err, "failed to read resource")
+	}
+	if len(resourceResponse.Contents) == 0 {
+		return nil, "", errors.New("resource response has no contents")
+	}
+	contents := resourceResponse.Contents[0]
+	if len(contents.Blob) < 2 || contents.Blob[0] != '"' || contents.Blob[len(contents.Blob)-1] != '"' {
+		return nil, "", errors.New("resource is not a blob resource")
 	}
 
-	responseBytes, ok := response.(json.RawMessage)
-	if !ok {
-		return nil, errors.New("invalid response type")
+	// Base64 has no characters that need escaping in JSON, so the quoted value can usually be decoded directly.
+	// Some encoders escape "/" anyway, in which case we fall back to unquoting the string.
+	encoded := contents.Blob[1 : len(contents.Blob)-1]
+	if bytes.IndexByte(encoded, '\\') >= 0 {
+		var unquoted string
+		if err := json.Unmarshal(contents.Blob, &unquoted); err != nil {
+			return nil, "", errors.Wrap(err, "failed to unmarshal blob")
+		}
+		encoded = []byte(unquoted)
 	}
 
-	var resourceResponse ResourceResponse
-	err = json.Unmarshal(responseBytes, &resourceResponse)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal resource response")
+	mimeType := ""
+	if contents.MimeType != nil {
+		mimeType = *contents.MimeType
 	}
+	return encoded, mimeType, nil
+}
 
-	return &resourceResponse, nil
+// Close closes the client's transport. Requests that are still waiting for a response fail.
+func (c *Client) Close() error {
+	return c.protocol.Close()
 }
 
 // Ping sends a ping request to the server to check connectivity
 func (c *Client) Ping(ctx context.Context) error {
 	if !c.initialized {
-		return errors.New("client not initialized")
+		return ErrClientNotInitialized
 	}
 
-	_, err := c.protocol.Request(ctx, "ping", nil, nil)
+	err := c.request(ctx, "ping", nil, nil, nil)
 	if err != nil {
 		return errors.Wrap(err, "failed to ping server")
 	}
@@ -277,7 +423,52 @@ func (c *Client) Ping(ctx context.Context) error {
 	return nil
 }
 
+// PingWithLatency pings the server and returns how long it took to get its response, e.g. to monitor the connection.
+func (c *Client) PingWithLatency(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if err := c.Ping(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// Describe lists the JSON-RPC methods the server handles.
+// The server must have been created with WithDescribeEndpoint, otherwise the request fails with method not found.
+func (c *Client) Describe(ctx context.Context) (*DescribeResponse, error) {
+	if !c.initialized {
+		return nil, ErrClientNotInitialized
+	}
+
+	var describeResponse DescribeResponse
+	err := c.request(ctx, "server/describe", nil, &describeResponse, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe server")
+	}
+
+	return &describeResponse, nil
+}
+
+// OnLogMessage registers a handler that is called for every notifications/message log message sent by the server.
+// Use LoggingMessageNotification.StructuredData or LoggingMessageNotification.Text to decode the data.
+// It should be called before Initialize so that no messages are missed.
+func (c *Client) OnLogMessage(handler func(notification *LoggingMessageNotification)) {
+	c.protocol.SetNotificationHandler("notifications/message", func(notification *transport.BaseJSONRPCNotification) error {
+		var logMessage LoggingMessageNotification
+		if err := json.Unmarshal(notification.Params, &logMessage); err != nil {
+			return errors.Wrap(err, "failed to unmarshal log message")
+		}
+		handler(&logMessage)
+		return nil
+	})
+}
+
 // GetCapabilities returns the server capabilities obtained during initialization
 func (c *Client) GetCapabilities() *ServerCapabilities {
 	return c.capabilities
 }
+
+// RawCapabilities returns the server capabilities obtained during initialization exactly as the server sent them.
+// Use it to read vendor or experimental capabilities that ServerCapabilities doesn't model.
+func (c *Client) RawCapabilities() json.RawMessage {
+	return c.rawCapabilities
+}
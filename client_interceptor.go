@@ -0,0 +1,49 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// ClientInterceptor wraps every request the client sends, e.g. to log, time or retry calls.
+// next sends the request and returns the raw result. An interceptor can run code before and after calling it,
+// call it several times, or return without calling it.
+type ClientInterceptor func(ctx context.Context, method string, params any, next func() (json.RawMessage, error)) (json.RawMessage, error)
+
+// WithClientInterceptor adds an interceptor that runs for every request the client sends, in the order given.
+// The first interceptor is the outermost.
+func WithClientInterceptor(interceptor ClientInterceptor) ClientOptions {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptor)
+	}
+}
+
+// request sends a request through the client's interceptors and unmarshals its result into out, unless out is nil
+func (c *Client) request(ctx context.Context, method string, params any, out any, opts *protocol.RequestOptions) error {
+	next := func() (json.RawMessage, error) {
+		var result json.RawMessage
+		err := c.protocol.RequestInto(ctx, method, params, &result, opts)
+		return result, err
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.interceptors[i], next
+		next = func() (json.RawMessage, error) {
+			return interceptor(ctx, method, params, inner)
+		}
+	}
+
+	result, err := next()
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result, out); err != nil {
+		return errors.Wrap(err, "failed to unmarshal response")
+	}
+	return nil
+}
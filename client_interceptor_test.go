@@ -0,0 +1,52 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithClientInterceptor(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	server := NewServer(nil)
+	require.NoError(t, server.RegisterTool("echo", "Echo a message", func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}))
+	serverTransport, clientTransport := newPipeTransports(t)
+	server.transport = serverTransport
+	require.NoError(t, server.Serve())
+
+	var calls []string
+	var observedParams any
+	var observedResult json.RawMessage
+	client := NewClient(clientTransport,
+		WithClientInterceptor(func(ctx context.Context, method string, params any, next func() (json.RawMessage, error)) (json.RawMessage, error) {
+			calls = append(calls, "outer "+method)
+			return next()
+		}),
+		WithClientInterceptor(func(ctx context.Context, method string, params any, next func() (json.RawMessage, error)) (json.RawMessage, error) {
+			calls = append(calls, "inner "+method)
+			result, err := next()
+			if method == "tools/call" {
+				observedParams, observedResult = params, result
+			}
+			return result, err
+		}),
+	)
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+
+	response, err := client.CallTool(context.Background(), "echo", args{Message: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", response.Content[0].TextContent.Text)
+
+	assert.Equal(t, []string{"outer initialize", "inner initialize", "outer tools/call", "inner tools/call"}, calls)
+	require.IsType(t, baseCallToolRequestParams{}, observedParams)
+	assert.Equal(t, "echo", observedParams.(baseCallToolRequestParams).Name)
+	assert.JSONEq(t, `{"content":[{"type":"text","text":"hi"}],"isError":false}`, string(observedResult))
+}
@@ -0,0 +1,207 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// GenerateClientStubs generates Go source of package pkgName with a typed function per tool of desc, e.g. from
+// Server.Describe. Each function takes a struct derived from the tool's input schema and calls the tool with Client.CallTool:
+//
+//	func GetWeather(ctx context.Context, client *mcp_golang.Client, arguments GetWeatherArguments) (*mcp_golang.ToolResponse, error)
+//
+// Optional scalar and object arguments are pointers, and schemas without a type are decoded as any.
+func GenerateClientStubs(desc ServerDescription, pkgName string) ([]byte, error) {
+	if !token.IsIdentifier(pkgName) {
+		return nil, errors.Errorf("invalid package name %q", pkgName)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by mcp_golang.GenerateClientStubs. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "import (\n\t\"context\"\n\n\tmcp_golang \"github.com/metoro-io/mcp-golang\"\n)\n")
+
+	names := map[string]bool{}
+	for _, tool := range desc.Tools {
+		schema, err := decodeStubSchema(tool.InputSchema)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid input schema of tool %s", tool.Name)
+		}
+		name := uniqueIdentifier(names, exportedIdentifier(tool.Name))
+		names[name+"Arguments"] = true
+
+		fmt.Fprintf(&buf, "\n// %sArguments are the arguments of the %s tool\n", name, tool.Name)
+		fmt.Fprintf(&buf, "type %sArguments %s\n", name, stubStructType(schema))
+
+		fmt.Fprintf(&buf, "\n// %s calls the %s tool.\n", name, tool.Name)
+		if tool.Description != nil && *tool.Description != "" {
+			fmt.Fprintf(&buf, "//\n%s", stubComment(*tool.Description))
+		}
+		fmt.Fprintf(&buf, "func %s(ctx context.Context, client *mcp_golang.Client, arguments %sArguments) (*mcp_golang.ToolResponse, error) {\n", name, name)
+		fmt.Fprintf(&buf, "\treturn client.CallTool(ctx, %s, arguments)\n}\n", strconv.Quote(tool.Name))
+	}
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format generated source")
+	}
+	return source, nil
+}
+
+// stubSchema is the subset of a JSON schema that client stubs are generated from
+type stubSchema struct {
+	Type        json.RawMessage        `json:"type"`
+	Description string                 `json:"description"`
+	Properties  map[string]*stubSchema `json:"properties"`
+	Required    []string               `json:"required"`
+	Items       *stubSchema            `json:"items"`
+}
+
+// decodeStubSchema decodes an input schema as listed in tools/list, whatever Go type holds it
+func decodeStubSchema(inputSchema any) (*stubSchema, error) {
+	data, err := json.Marshal(inputSchema)
+	if err != nil {
+		return nil, err
+	}
+	schema := &stubSchema{}
+	if string(data) == "null" {
+		return schema, nil
+	}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// schemaType returns the type of a schema, the first type other than null if it lists several
+func (s *stubSchema) schemaType() string {
+	var single string
+	if json.Unmarshal(s.Type, &single) == nil {
+		return single
+	}
+	var types []string
+	if json.Unmarshal(s.Type, &types) == nil {
+		for _, t := range types {
+			if t != "null" {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// stubGoType returns the Go type of values of schema, a pointer for optional scalars and objects
+func stubGoType(schema *stubSchema, required bool) string {
+	var goType string
+	switch schema.schemaType() {
+	case "string":
+		goType = "string"
+	case "integer":
+		goType = "int64"
+	case "number":
+		goType = "float64"
+	case "boolean":
+		goType = "bool"
+	case "array":
+		if schema.Items == nil {
+			return "[]any"
+		}
+		return "[]" + stubGoType(schema.Items, true)
+	case "object":
+		if len(schema.Properties) == 0 {
+			return "map[string]any"
+		}
+		goType = stubStructType(schema)
+	default:
+		return "any"
+	}
+	if !required {
+		return "*" + goType
+	}
+	return goType
+}
+
+// stubStructType returns a struct type with a field per property of schema, ordered by name
+func stubStructType(schema *stubSchema) string {
+	if len(schema.Properties) == 0 {
+		return "struct{}"
+	}
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	properties := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		properties = append(properties, name)
+	}
+	sort.Strings(properties)
+
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	fields := map[string]bool{}
+	for _, property := range properties {
+		propertySchema := schema.Properties[property]
+		if propertySchema == nil {
+			propertySchema = &stubSchema{}
+		}
+		if propertySchema.Description != "" {
+			b.WriteString(stubComment(propertySchema.Description))
+		}
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s %s `json:%s`\n", uniqueIdentifier(fields, exportedIdentifier(property)), stubGoType(propertySchema, required[property]), strconv.Quote(tag))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// stubComment formats text as a line comment
+func stubComment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		b.WriteString(strings.TrimRight("// "+strings.TrimSpace(line), " ") + "\n")
+	}
+	return b.String()
+}
+
+// exportedIdentifier turns a tool or property name such as get_weather into an exported Go identifier such as GetWeather
+func exportedIdentifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	identifier := b.String()
+	if identifier == "" || !unicode.IsUpper([]rune(identifier)[0]) {
+		identifier = "X" + identifier
+	}
+	return identifier
+}
+
+// uniqueIdentifier returns identifier, with a number appended if it is already taken, and marks it as taken
+func uniqueIdentifier(taken map[string]bool, identifier string) string {
+	unique := identifier
+	for i := 2; taken[unique]; i++ {
+		unique = identifier + strconv.Itoa(i)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,58 @@
+package mcp_golang
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubWeatherArgs struct {
+	City  string   `json:"city" jsonschema:"required,description=The city to get the weather of"`
+	Days  *int     `json:"days,omitempty"`
+	Units []string `json:"units,omitempty"`
+}
+
+type stubEchoArgs struct {
+	Message string `json:"message" jsonschema:"required"`
+	Options struct {
+		Uppercase bool `json:"uppercase"`
+	} `json:"options"`
+}
+
+func TestGenerateClientStubs(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	require.NoError(t, server.RegisterTool("get_weather", "Gets the weather\nof a city", func(arguments stubWeatherArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.City)), nil
+	}))
+	require.NoError(t, server.RegisterTool("echo", "Echoes a message", func(arguments stubEchoArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}))
+
+	source, err := GenerateClientStubs(server.Describe(), "stubs")
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "// Code generated by mcp_golang.GenerateClientStubs. DO NOT EDIT.")
+	assert.Contains(t, string(source), "func GetWeather(ctx context.Context, client *mcp_golang.Client, arguments GetWeatherArguments) (*mcp_golang.ToolResponse, error)")
+	assert.Contains(t, string(source), "func Echo(ctx context.Context, client *mcp_golang.Client, arguments EchoArguments) (*mcp_golang.ToolResponse, error)")
+	assert.Regexp(t, `City\s+string\s+`+"`json:\"city\"`", string(source))
+	assert.Regexp(t, `Days\s+\*int64\s+`+"`json:\"days,omitempty\"`", string(source))
+
+	// The stubs are type-checked against this module's source, without writing them anywhere
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "stubs.go", source, parser.ParseComments)
+	require.NoError(t, err, "Failed to parse stubs:\n%s", source)
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = config.Check("stubs", fset, []*ast.File{file}, nil)
+	require.NoError(t, err, "Failed to type-check stubs:\n%s", source)
+}
+
+func TestGenerateClientStubsInvalidPackageName(t *testing.T) {
+	_, err := GenerateClientStubs(ServerDescription{}, "not a package")
+	assert.Error(t, err)
+}
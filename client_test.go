@@ -0,0 +1,541 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/metoro-io/mcp-golang/transport/stdio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientInitializeTimeout(t *testing.T) {
+	t.Run("server never responds", func(t *testing.T) {
+		client := NewClient(testingutils.NewMockTransport(), WithInitializeTimeout(50*time.Millisecond))
+
+		start := time.Now()
+		_, err := client.Initialize(context.Background())
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Contains(t, err.Error(), "no valid response from the server after")
+		assert.Contains(t, err.Error(), "request timeout")
+	})
+
+	t.Run("context cancellation aborts initialize", func(t *testing.T) {
+		client := NewClient(testingutils.NewMockTransport())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		_, err := client.Initialize(ctx)
+		assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	})
+
+	t.Run("errors sent by the server are passed through", func(t *testing.T) {
+		server := protocol.NewProtocol(nil)
+		server.SetRequestHandler("initialize", func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+			return nil, protocol.NewRPCError(protocol.ErrorCodeInvalidParams, "unsupported protocol version", nil)
+		})
+		serverTransport, clientTransport := newPipeTransports(t)
+		require.NoError(t, server.Connect(serverTransport))
+
+		_, err := NewClient(clientTransport).Initialize(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported protocol version")
+		assert.NotContains(t, err.Error(), "no valid response")
+	})
+}
+
+// newPipeTransports returns a server and a client transport connected to each other over an in-memory stdio pipe
+func newPipeTransports(t *testing.T) (*stdio.StdioServerTransport, *stdio.StdioServerTransport) {
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	t.Cleanup(func() {
+		clientWriter.Close()
+		serverWriter.Close()
+	})
+	return stdio.NewStdioServerTransportWithIO(serverReader, serverWriter), stdio.NewStdioServerTransportWithIO(clientReader, clientWriter)
+}
+
+// newInProcessClient serves the server over an in-memory stdio pipe and returns an initialized client connected to it
+func newInProcessClient(t *testing.T, server *Server) *Client {
+	serverTransport, clientTransport := newPipeTransports(t)
+	server.transport = serverTransport
+	require.NoError(t, server.Serve())
+
+	client := NewClient(clientTransport)
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+	return client
+}
+
+func TestClientWithContext(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	release := make(chan struct{})
+	defer close(release)
+	server := NewServer(nil)
+	err := server.RegisterTool("block", "Never returns on its own", func(arguments args) (*ToolResponse, error) {
+		<-release
+		return NewToolResponse(), nil
+	})
+	require.NoError(t, err)
+	serverTransport, clientTransport := newPipeTransports(t)
+	server.transport = serverTransport
+	require.NoError(t, server.Serve())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(clientTransport, WithContext(ctx))
+	_, err = client.Initialize(context.Background())
+	require.NoError(t, err)
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := client.CallTool(context.Background(), "block", args{})
+		callErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	// Cancelling the parent context closes the client, which fails the pending call
+	select {
+	case err := <-callErr:
+		assert.ErrorIs(t, err, protocol.ErrConnectionClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Pending call did not fail after the parent context was cancelled")
+	}
+}
+
+func TestClientReadResourceBlob(t *testing.T) {
+	data := make([]byte, 64*1024)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	server := NewServer(nil)
+	err := server.RegisterResource("file:///image.png", "image", "A binary file", "image/png", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewBlobEmbeddedResource("file:///image.png", base64.StdEncoding.EncodeToString(data), "image/png")), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	decoded, mimeType, err := client.ReadResourceBlob(context.Background(), "file:///image.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, data, decoded)
+
+	reader, mimeType, err := client.ReadResourceBlobReader(context.Background(), "file:///image.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	streamed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, streamed)
+}
+
+func TestClientReadResourceMultipleContents(t *testing.T) {
+	server := NewServer(nil)
+	err := server.RegisterResource("file:///docs/", "docs", "A directory", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(
+			NewTextEmbeddedResource("file:///docs/a.md", "# A", "text/markdown"),
+			NewTextEmbeddedResource("file:///docs/b.txt", "b", "text/plain"),
+			NewBlobEmbeddedResource("file:///docs/c.png", base64.StdEncoding.EncodeToString([]byte{1, 2, 3}), "image/png"),
+		), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	response, err := client.ReadResource(context.Background(), "file:///docs/")
+	require.NoError(t, err)
+	require.Len(t, response.Contents, 3)
+	assert.Equal(t, "file:///docs/a.md", response.Contents[0].TextResourceContents.Uri)
+	assert.Equal(t, "# A", response.Contents[0].TextResourceContents.Text)
+	assert.Equal(t, "file:///docs/b.txt", response.Contents[1].TextResourceContents.Uri)
+	assert.Equal(t, "b", response.Contents[1].TextResourceContents.Text)
+	assert.Equal(t, "file:///docs/c.png", response.Contents[2].BlobResourceContents.Uri)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{1, 2, 3}), response.Contents[2].BlobResourceContents.Blob)
+}
+
+func TestClientReadResourceETag(t *testing.T) {
+	lastModified := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+	server := NewServer(nil)
+	err := server.RegisterResource("file:///config.json", "config", "Configuration", "application/json", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///config.json", `{"debug":true}`, "application/json")).
+			WithSize(14).
+			WithETag("v1").
+			WithLastModified(lastModified), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	// Metadata is sent along with the contents
+	response, err := client.ReadResource(context.Background(), "file:///config.json")
+	require.NoError(t, err)
+	require.Len(t, response.Contents, 1)
+	assert.False(t, response.NotModified)
+	require.NotNil(t, response.Size)
+	assert.Equal(t, int64(14), *response.Size)
+	require.NotNil(t, response.ETag)
+	assert.Equal(t, "v1", *response.ETag)
+	require.NotNil(t, response.LastModified)
+	assert.True(t, lastModified.Equal(*response.LastModified))
+
+	// A matching etag skips the contents
+	response, err = client.ReadResourceIfModified(context.Background(), "file:///config.json", "v1")
+	require.NoError(t, err)
+	assert.True(t, response.NotModified)
+	assert.Empty(t, response.Contents)
+	assert.Equal(t, "v1", *response.ETag)
+
+	// A stale etag returns the contents
+	response, err = client.ReadResourceIfModified(context.Background(), "file:///config.json", "v0")
+	require.NoError(t, err)
+	assert.False(t, response.NotModified)
+	assert.Len(t, response.Contents, 1)
+}
+
+func TestClientReadResourceRange(t *testing.T) {
+	contents := "0123456789abcdef"
+	server := NewServer(nil)
+	err := server.RegisterResource("file:///data.txt", "data", "Supports ranges", "text/plain", func(ctx context.Context) (*ResourceResponse, error) {
+		byteRange := ResourceRangeFromContext(ctx)
+		if byteRange == nil {
+			return NewResourceResponse(NewTextEmbeddedResource("file:///data.txt", contents, "text/plain")).WithSize(int64(len(contents))), nil
+		}
+		end := min(byteRange.End, int64(len(contents)))
+		return NewResourceResponse(NewTextEmbeddedResource("file:///data.txt", contents[byteRange.Start:end], "text/plain")).
+			WithSize(int64(len(contents))).
+			WithRange(byteRange.Start, end), nil
+	})
+	require.NoError(t, err)
+	err = server.RegisterResource("file:///full.txt", "full", "Ignores ranges", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///full.txt", contents, "text/plain")), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	// A handler that honors the range returns part of the contents and the total size
+	response, err := client.ReadResourceRange(context.Background(), "file:///data.txt", 4, 8)
+	require.NoError(t, err)
+	require.Len(t, response.Contents, 1)
+	assert.Equal(t, "4567", response.Contents[0].TextResourceContents.Text)
+	require.NotNil(t, response.Range)
+	assert.Equal(t, ResourceRange{Start: 4, End: 8}, *response.Range)
+	require.NotNil(t, response.Size)
+	assert.Equal(t, int64(16), *response.Size)
+
+	// A handler that ignores the range returns the full contents
+	response, err = client.ReadResourceRange(context.Background(), "file:///full.txt", 4, 8)
+	require.NoError(t, err)
+	require.Len(t, response.Contents, 1)
+	assert.Equal(t, contents, response.Contents[0].TextResourceContents.Text)
+	assert.Nil(t, response.Range)
+
+	// Without a range the full contents are returned
+	response, err = client.ReadResource(context.Background(), "file:///data.txt")
+	require.NoError(t, err)
+	assert.Equal(t, contents, response.Contents[0].TextResourceContents.Text)
+	assert.Nil(t, response.Range)
+
+	// Empty and negative ranges are rejected
+	_, err = client.ReadResourceRange(context.Background(), "file:///data.txt", 8, 4)
+	assert.Error(t, err)
+}
+
+func TestClientCallToolInBandError(t *testing.T) {
+	type args struct {
+		Path string `json:"path"`
+	}
+
+	server := NewServer(nil)
+	err := server.RegisterTool("read_file", "Read a file", func(arguments args) (*ToolResponse, error) {
+		response, err := NewToolResponseBuilder().AddText(arguments.Path + " does not exist").AsError().Build()
+		if err != nil {
+			return nil, err
+		}
+		// The response takes precedence over the error
+		return response, errors.New("file not found")
+	})
+	require.NoError(t, err)
+	err = server.RegisterTool("fail", "Always fails", func(arguments args) (*ToolResponse, error) {
+		return nil, errors.New("database unavailable")
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	response, err := client.CallTool(context.Background(), "read_file", args{Path: "/tmp/missing"})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	require.Len(t, response.Content, 1)
+	assert.Equal(t, "/tmp/missing does not exist", response.Content[0].TextContent.Text)
+
+	response, err = client.CallTool(context.Background(), "fail", args{})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	require.Len(t, response.Content, 1)
+	assert.Contains(t, response.Content[0].TextContent.Text, "database unavailable")
+}
+
+func TestClientRawCapabilities(t *testing.T) {
+	serverTransport, clientTransport := newPipeTransports(t)
+	serverProtocol := protocol.NewProtocol(nil)
+	serverProtocol.SetRequestHandler("initialize", func(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return json.RawMessage(`{
+			"protocolVersion": "2024-11-05",
+			"serverInfo": {"name": "vendor-server", "version": "1.0.0"},
+			"capabilities": {"tools": {"listChanged": true}, "vendor.example/streaming": {"maxChunkSize": 1024}}
+		}`), nil
+	})
+	require.NoError(t, serverProtocol.Connect(serverTransport))
+
+	client := NewClient(clientTransport)
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+
+	require.NotNil(t, client.GetCapabilities().Tools)
+	assert.JSONEq(t, `{"tools": {"listChanged": true}, "vendor.example/streaming": {"maxChunkSize": 1024}}`, string(client.RawCapabilities()))
+}
+
+func TestRegisterPromptTyped(t *testing.T) {
+	type reviewArgs struct {
+		Language string  `json:"language" jsonschema:"required,description=The programming language"`
+		Focus    *string `json:"focus" jsonschema:"description=What to focus on"`
+	}
+
+	server := NewServer(nil)
+	err := RegisterPromptTyped(server, "review", "Review code", func(args reviewArgs) (*PromptResponse, error) {
+		text := "Review this " + args.Language + " code"
+		if args.Focus != nil {
+			text += " for " + *args.Focus
+		}
+		return NewPromptResponse("review", NewPromptMessage(NewTextContent(text), RoleUser)), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	prompts, err := client.ListPrompts(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, prompts.Prompts, 1)
+	require.Len(t, prompts.Prompts[0].Arguments, 2)
+	assert.True(t, *prompts.Prompts[0].Arguments[0].Required)
+	assert.Equal(t, "The programming language", *prompts.Prompts[0].Arguments[0].Description)
+	assert.False(t, *prompts.Prompts[0].Arguments[1].Required)
+
+	response, err := client.GetPrompt(context.Background(), "review", map[string]string{"language": "Go", "focus": "races"})
+	require.NoError(t, err)
+	require.Len(t, response.Messages, 1)
+	assert.Equal(t, "Review this Go code for races", response.Messages[0].Content.TextContent.Text)
+
+	// A missing required argument is reported instead of calling the handler
+	response, err = client.GetPrompt(context.Background(), "review", map[string]string{})
+	require.NoError(t, err)
+	assert.Contains(t, response.Messages[0].Content.TextContent.Text, "missing required argument")
+
+	// Argument types are checked at registration
+	err = RegisterPromptTyped(server, "bad", "Bad prompt", func(args struct{ Count int }) (*PromptResponse, error) {
+		return nil, nil
+	})
+	assert.Error(t, err)
+}
+
+func TestClientListToolsMeta(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	server := NewServer(nil)
+	meta := map[string]any{
+		"category": "text",
+		"icon":     "https://example.com/echo.svg",
+		"tags":     []any{"demo", "echo"},
+		"vendor":   map[string]any{"x-rank": float64(3), "beta": true},
+	}
+	err := server.RegisterTool("echo", "Echo the message", func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}, WithToolMeta(meta))
+	require.NoError(t, err)
+	err = server.RegisterTool("plain", "No metadata", func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	tools, err := client.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 2)
+	assert.Equal(t, "echo", tools.Tools[0].Name)
+	assert.Equal(t, meta, tools.Tools[0].Meta)
+	assert.Nil(t, tools.Tools[1].Meta)
+
+	// Tools without metadata are listed without a _meta field
+	marshalled, err := json.Marshal(tools.Tools[1])
+	require.NoError(t, err)
+	assert.NotContains(t, string(marshalled), "_meta")
+}
+
+func TestClientPingWithLatency(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	client := NewClient(mockTransport)
+	require.NoError(t, client.protocol.Connect(mockTransport))
+	client.initialized = true
+
+	// Answer the ping after a delay
+	delay := 20 * time.Millisecond
+	go func() {
+		for len(mockTransport.GetMessages()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(delay)
+		mockTransport.SimulateMessage(transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      mockTransport.GetMessages()[0].JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{}`),
+		}))
+	}()
+
+	latency, err := client.PingWithLatency(context.Background())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, latency, delay)
+	assert.Less(t, latency, time.Second)
+
+	// The ping gives up when the context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	latency, err = client.PingWithLatency(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, latency)
+}
+
+func TestClientNotificationHandlerError(t *testing.T) {
+	server := NewServer(nil)
+	serverTransport, clientTransport := newPipeTransports(t)
+	server.transport = serverTransport
+	require.NoError(t, server.Serve())
+
+	errMalformed := errors.New("malformed progress")
+	received := make(chan json.RawMessage, 1)
+	handlerErrors := make(chan error, 1)
+	client := NewClient(clientTransport,
+		WithNotificationHandler("notifications/custom", func(params json.RawMessage) error {
+			received <- params
+			return errMalformed
+		}),
+		WithErrorHandler(func(err error) {
+			select {
+			case handlerErrors <- err:
+			default:
+			}
+		}),
+	)
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, serverTransport.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/custom",
+		Params:  json.RawMessage(`{"progress":"half"}`),
+	})))
+
+	select {
+	case params := <-received:
+		assert.JSONEq(t, `{"progress":"half"}`, string(params))
+	case <-time.After(time.Second):
+		t.Fatal("Notification handler was not called")
+	}
+	select {
+	case err := <-handlerErrors:
+		assert.ErrorIs(t, err, errMalformed)
+	case <-time.After(time.Second):
+		t.Fatal("Notification handler error was not reported")
+	}
+}
+
+func TestNotifyAfterResponse(t *testing.T) {
+	type args struct{}
+	server := NewServer(nil)
+	require.NoError(t, server.RegisterTool("export", "Starts an export", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		if err := NotifyAfterResponse(ctx, "notifications/export_complete", map[string]string{"file": "export.csv"}); err != nil {
+			return nil, err
+		}
+		return NewToolResponse(NewTextContent("export started")), nil
+	}))
+	serverTransport, clientTransport := newPipeTransports(t)
+	server.transport = serverTransport
+	require.NoError(t, server.Serve())
+
+	notifications := make(chan json.RawMessage, 1)
+	client := NewClient(clientTransport, WithNotificationHandler("notifications/export_complete", func(params json.RawMessage) error {
+		notifications <- params
+		return nil
+	}))
+	_, err := client.Initialize(context.Background())
+	require.NoError(t, err)
+
+	response, err := client.CallTool(context.Background(), "export", args{})
+	require.NoError(t, err)
+	assert.Equal(t, "export started", response.Content[0].TextContent.Text)
+	select {
+	case params := <-notifications:
+		assert.JSONEq(t, `{"file":"export.csv"}`, string(params))
+	case <-time.After(time.Second):
+		t.Fatal("Notification was not sent after the response")
+	}
+}
+
+func TestServerCloseHandler(t *testing.T) {
+	serverReader, clientWriter := io.Pipe()
+	closed := make(chan struct{})
+	server := NewServer(stdio.NewStdioServerTransportWithIO(serverReader, io.Discard), WithCloseHandler(func() {
+		close(closed)
+	}))
+	require.NoError(t, server.Serve())
+
+	// The client going away closes the server's input
+	require.NoError(t, clientWriter.Close())
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close handler was not called when the client closed the server's input")
+	}
+}
+
+func TestClientCapabilityGating(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	server := NewServer(nil)
+	err := server.RegisterTool("echo", "Echo a message", func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	// Only tools are registered, so only tools are advertised
+	capabilities := client.GetCapabilities()
+	require.NotNil(t, capabilities.Tools)
+	require.NotNil(t, capabilities.Tools.ListChanged)
+	assert.True(t, *capabilities.Tools.ListChanged)
+	assert.Nil(t, capabilities.Prompts)
+	assert.Nil(t, capabilities.Resources)
+
+	_, err = client.ListTools(context.Background(), nil)
+	assert.NoError(t, err)
+	_, err = client.ListPrompts(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrPromptsNotSupported)
+	_, err = client.GetPrompt(context.Background(), "greeting", nil)
+	assert.ErrorIs(t, err, ErrPromptsNotSupported)
+	_, err = client.ListResources(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrResourcesNotSupported)
+	_, err = client.ReadResource(context.Background(), "file:///a.txt")
+	assert.ErrorIs(t, err, ErrResourcesNotSupported)
+}
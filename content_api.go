@@ -3,6 +3,9 @@ package mcp_golang
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 
 	"github.com/tidwall/sjson"
 )
@@ -43,6 +46,21 @@ type ImageContent struct {
 	MimeType string `json:"mimeType" yaml:"mimeType" mapstructure:"mimeType"`
 }
 
+// A link to a resource that the client can read, sent instead of embedding the resource's contents.
+type ResourceLinkContent struct {
+	// The URI of the resource.
+	Uri string `json:"uri" yaml:"uri" mapstructure:"uri"`
+
+	// A human-readable name for the resource.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// A description of what the resource represents.
+	Description *string `json:"description,omitempty" yaml:"description,omitempty" mapstructure:"description,omitempty"`
+
+	// The MIME type of the resource, if known.
+	MimeType *string `json:"mimeType,omitempty" yaml:"mimeType,omitempty" mapstructure:"mimeType,omitempty"`
+}
+
 type embeddedResourceType string
 
 const (
@@ -125,6 +143,7 @@ const (
 	ContentTypeText             ContentType = "text"
 	ContentTypeImage            ContentType = "image"
 	ContentTypeEmbeddedResource ContentType = "resource"
+	ContentTypeResourceLink     ContentType = "resource_link"
 )
 
 type Content struct {
@@ -132,7 +151,32 @@ type Content struct {
 	TextContent      *TextContent
 	ImageContent     *ImageContent
 	EmbeddedResource *EmbeddedResource
+	ResourceLink     *ResourceLinkContent
 	Annotations      *Annotations
+	// Raw holds the JSON object of content types that this library does not model, so that they round-trip without loss
+	Raw json.RawMessage
+
+	// Set by NewTextContentReader, the text is read when the content is marshaled
+	textReader *textReader
+}
+
+// textReader reads the text of reader-backed text content once, so that the content can be marshaled more than once
+type textReader struct {
+	once     sync.Once
+	reader   io.Reader
+	mimeType string
+	text     string
+	err      error
+}
+
+func (r *textReader) read() (string, error) {
+	r.once.Do(func() {
+		var builder strings.Builder
+		_, r.err = io.Copy(&builder, r.reader)
+		r.text = builder.String()
+		r.reader = nil
+	})
+	return r.text, r.err
 }
 
 func (c *Content) UnmarshalJSON(b []byte) error {
@@ -155,13 +199,46 @@ func (c *Content) UnmarshalJSON(b []byte) error {
 		c.Type = ContentTypeImage
 	case ContentTypeEmbeddedResource:
 		c.Type = ContentTypeEmbeddedResource
+	case ContentTypeResourceLink:
+		c.Type = ContentTypeResourceLink
+	case "":
+		return fmt.Errorf("content is missing a type")
 	default:
-		return fmt.Errorf("unknown content type: %s", tw.Type)
+		// Keep content types we don't know about as is
+		c.Type = tw.Type
+		c.Annotations = tw.Annotations
+		c.Raw = append(json.RawMessage(nil), b...)
+		return nil
 	}
 
 	switch c.Type {
 	case ContentTypeText:
 		c.TextContent = &TextContent{Text: *tw.Text}
+	case ContentTypeImage:
+		var image ImageContent
+		if err := json.Unmarshal(b, &image); err != nil {
+			return err
+		}
+		c.ImageContent = &image
+		c.Annotations = tw.Annotations
+	case ContentTypeEmbeddedResource:
+		// The resource is either nested under "resource", or its fields are inlined as this library sends them
+		c.EmbeddedResource = tw.EmbeddedResource
+		if c.EmbeddedResource == nil {
+			var resource EmbeddedResource
+			if err := json.Unmarshal(b, &resource); err != nil {
+				return err
+			}
+			c.EmbeddedResource = &resource
+		}
+		c.Annotations = tw.Annotations
+	case ContentTypeResourceLink:
+		var link ResourceLinkContent
+		if err := json.Unmarshal(b, &link); err != nil {
+			return err
+		}
+		c.ResourceLink = &link
+		c.Annotations = tw.Annotations
 	default:
 		return fmt.Errorf("unknown content type: %s", c.Type)
 	}
@@ -175,10 +252,26 @@ func (c Content) MarshalJSON() ([]byte, error) {
 
 	switch c.Type {
 	case ContentTypeText:
-		j, err := json.Marshal(c.TextContent)
+		textContent := c.TextContent
+		var mimeType string
+		if c.textReader != nil {
+			text, err := c.textReader.read()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read text content: %w", err)
+			}
+			text, mimeType = transcodeText(text, c.textReader.mimeType)
+			textContent = &TextContent{Text: text}
+		}
+		j, err := json.Marshal(textContent)
 		if err != nil {
 			return nil, err
 		}
+		if mimeType != "" {
+			j, err = sjson.SetBytes(j, "mimeType", mimeType)
+			if err != nil {
+				return nil, err
+			}
+		}
 		rawJson = j
 	case ContentTypeImage:
 		j, err := json.Marshal(c.ImageContent)
@@ -192,8 +285,17 @@ func (c Content) MarshalJSON() ([]byte, error) {
 			return nil, err
 		}
 		rawJson = j
+	case ContentTypeResourceLink:
+		j, err := json.Marshal(c.ResourceLink)
+		if err != nil {
+			return nil, err
+		}
+		rawJson = j
 	default:
-		return nil, fmt.Errorf("unknown content type: %s", c.Type)
+		if c.Raw == nil {
+			return nil, fmt.Errorf("unknown content type: %s", c.Type)
+		}
+		rawJson = append([]byte(nil), c.Raw...)
 	}
 
 	// Add the type
@@ -207,7 +309,7 @@ func (c Content) MarshalJSON() ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		rawJson, err = sjson.SetBytes(rawJson, "annotations", marshal)
+		rawJson, err = sjson.SetRawBytes(rawJson, "annotations", marshal)
 		if err != nil {
 			return nil, err
 		}
@@ -244,6 +346,28 @@ func NewImageContent(base64EncodedStringData string, mimeType string) *Content {
 	}
 }
 
+// NewCustomContent creates a new ToolResponse content of a type that this library does not model.
+// The data must marshal to a JSON object; its fields are sent alongside the given type.
+// Only use this for content types that the client is known to understand.
+func NewCustomContent(contentType ContentType, data any) (*Content, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("custom content must be a JSON object: %w", err)
+	}
+	raw, err = sjson.SetBytes(raw, "type", string(contentType))
+	if err != nil {
+		return nil, err
+	}
+	return &Content{
+		Type: contentType,
+		Raw:  raw,
+	}, nil
+}
+
 // NewTextContent creates a new ToolResponse that is a simple text string.
 // The client will render this as a single string.
 func NewTextContent(content string) *Content {
@@ -253,6 +377,34 @@ func NewTextContent(content string) *Content {
 	}
 }
 
+// NewTextContentReader creates a new ToolResponse that is text read from r, e.g. a file or a log stream,
+// so that tools don't have to build the text as a string themselves.
+// The transports in this library send whole messages, so r is read to its end when the response is marshaled.
+// A non-empty mimeType is sent alongside the text. If it has a charset parameter, e.g. "text/plain; charset=iso-8859-1",
+// the text is transcoded from that charset to UTF-8.
+func NewTextContentReader(r io.Reader, mimeType string) *Content {
+	return &Content{
+		Type:       ContentTypeText,
+		textReader: &textReader{reader: r, mimeType: mimeType},
+	}
+}
+
+// NewResourceLinkContent creates a new ToolResponse content that links to a resource instead of embedding it,
+// so that the client can read the resource when it needs it. Empty description and mimeType are omitted.
+func NewResourceLinkContent(uri string, name string, description string, mimeType string) *Content {
+	link := &ResourceLinkContent{Uri: uri, Name: name}
+	if description != "" {
+		link.Description = &description
+	}
+	if mimeType != "" {
+		link.MimeType = &mimeType
+	}
+	return &Content{
+		Type:         ContentTypeResourceLink,
+		ResourceLink: link,
+	}
+}
+
 // NewBlobResourceContent creates a new ToolResponse that is a blob of binary data.
 // The given data is base64-encoded; the client will decode it.
 // The client will render this as a blob; it will not be human-readable.
@@ -272,6 +424,7 @@ func NewBlobResourceContent(uri string, base64EncodedData string, mimeType strin
 // NewTextResourceContent creates a new ToolResponse that is an embedded resource of type "text".
 // The given text is embedded in the response as a TextResourceContents, which
 // contains the given MIME type and URI. The text is not base64-encoded.
+// If the MIME type has a charset parameter other than utf-8, the text is transcoded from that charset to UTF-8 when the server sends it.
 func NewTextResourceContent(uri string, text string, mimeType string) *Content {
 	return &Content{
 		Type: ContentTypeEmbeddedResource,
@@ -0,0 +1,167 @@
+package mcp_golang
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentUnknownTypeRoundTrip(t *testing.T) {
+	original := `{"type":"chart","spec":{"kind":"bar","values":[1,2,3]},"title":"Sales","annotations":{"priority":0.5}}`
+
+	var content Content
+	require.NoError(t, json.Unmarshal([]byte(original), &content))
+	assert.Equal(t, ContentType("chart"), content.Type)
+	require.NotNil(t, content.Annotations)
+	assert.Equal(t, 0.5, *content.Annotations.Priority)
+
+	marshalled, err := json.Marshal(content)
+	require.NoError(t, err)
+	assert.JSONEq(t, original, string(marshalled))
+
+	// Unknown content also survives as part of a tool response
+	var response ToolResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"content":[{"type":"text","text":"hi"},`+original+`]}`), &response))
+	require.Len(t, response.Content, 2)
+	marshalled, err = json.Marshal(response.Content[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, original, string(marshalled))
+}
+
+func TestNewCustomContent(t *testing.T) {
+	content, err := NewCustomContent("chart", map[string]any{"title": "Sales"})
+	require.NoError(t, err)
+
+	marshalled, err := json.Marshal(content)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"chart","title":"Sales"}`, string(marshalled))
+
+	_, err = NewCustomContent("chart", []int{1, 2})
+	assert.Error(t, err)
+}
+
+func TestResourceLinkContentRoundTrip(t *testing.T) {
+	content := NewResourceLinkContent("file:///logs/today.log", "today.log", "Today's server logs", "text/plain")
+
+	marshalled, err := json.Marshal(content)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"resource_link","uri":"file:///logs/today.log","name":"today.log","description":"Today's server logs","mimeType":"text/plain"}`, string(marshalled))
+
+	var decoded Content
+	require.NoError(t, json.Unmarshal(marshalled, &decoded))
+	assert.Equal(t, ContentTypeResourceLink, decoded.Type)
+	assert.Equal(t, content.ResourceLink, decoded.ResourceLink)
+
+	// Optional fields are omitted
+	marshalled, err = json.Marshal(NewResourceLinkContent("file:///data.csv", "data.csv", "", ""))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"resource_link","uri":"file:///data.csv","name":"data.csv"}`, string(marshalled))
+
+	// Links can be sent in tool and prompt responses
+	toolResponse := NewToolResponse(NewTextContent("See the logs"), content)
+	marshalled, err = json.Marshal(toolResponse)
+	require.NoError(t, err)
+	var decodedToolResponse ToolResponse
+	require.NoError(t, json.Unmarshal(marshalled, &decodedToolResponse))
+	require.Len(t, decodedToolResponse.Content, 2)
+	assert.Equal(t, content.ResourceLink, decodedToolResponse.Content[1].ResourceLink)
+
+	promptResponse := NewPromptResponse("logs", NewPromptMessage(content, RoleUser))
+	marshalled, err = json.Marshal(promptResponse)
+	require.NoError(t, err)
+	var decodedPromptResponse PromptResponse
+	require.NoError(t, json.Unmarshal(marshalled, &decodedPromptResponse))
+	require.Len(t, decodedPromptResponse.Messages, 1)
+	assert.Equal(t, content.ResourceLink, decodedPromptResponse.Messages[0].Content.ResourceLink)
+}
+
+func TestNewTextContentReader(t *testing.T) {
+	// Large enough to span many reads, with characters that need escaping in JSON
+	line := "level=info msg=\"request served\" path=/weather\ttook=12ms <ok> ✓\n"
+	text := strings.Repeat(line, 100000)
+	content := NewTextContentReader(strings.NewReader(text), "text/plain")
+
+	marshalled, err := json.Marshal(NewToolResponse(content))
+	require.NoError(t, err)
+	var response ToolResponse
+	require.NoError(t, json.Unmarshal(marshalled, &response))
+	require.Len(t, response.Content, 1)
+	assert.Equal(t, ContentTypeText, response.Content[0].Type)
+	assert.Equal(t, text, response.Content[0].TextContent.Text)
+	assert.Contains(t, string(marshalled[len(marshalled)-200:]), `"mimeType":"text/plain"`)
+
+	// The reader is only consumed once, so the content can be marshaled again
+	again, err := json.Marshal(NewToolResponse(content))
+	require.NoError(t, err)
+	assert.Equal(t, marshalled, again)
+
+	// Read errors fail marshaling rather than sending truncated text
+	_, err = json.Marshal(NewTextContentReader(iotest.ErrReader(errors.New("disk failure")), ""))
+	assert.ErrorContains(t, err, "disk failure")
+}
+
+func TestTextContentCharset(t *testing.T) {
+	// "café" and "naïve" in Latin-1, which is not valid UTF-8
+	latin1 := "caf\xe9 na\xefve"
+
+	marshalled, err := json.Marshal(NewTextContentReader(strings.NewReader(latin1), "text/plain; charset=ISO-8859-1"))
+	require.NoError(t, err)
+	require.True(t, json.Valid(marshalled))
+	var content Content
+	require.NoError(t, json.Unmarshal(marshalled, &content))
+	assert.Equal(t, "café naïve", content.TextContent.Text)
+	assert.Contains(t, string(marshalled), `"mimeType":"text/plain; charset=utf-8"`)
+
+	// Embedded resources are transcoded when the server sends them
+	sendResource := func(text string, mimeType string) *TextResourceContents {
+		marshalled, err := json.Marshal(newToolResponseSent(NewToolResponse(NewTextResourceContent("file:///notes.txt", text, mimeType))))
+		require.NoError(t, err, mimeType)
+		require.True(t, json.Valid(marshalled), mimeType)
+		var response ToolResponse
+		require.NoError(t, json.Unmarshal(marshalled, &response), mimeType)
+		return response.Content[0].EmbeddedResource.TextResourceContents
+	}
+	received := sendResource(latin1, "text/plain; charset=latin1")
+	assert.Equal(t, "café naïve", received.Text)
+	assert.Equal(t, "text/plain; charset=utf-8", *received.MimeType)
+
+	// Invalid sequences are replaced rather than producing invalid JSON
+	for _, mimeType := range []string{"text/plain; charset=utf-8", "text/plain; charset=us-ascii", "text/plain; charset=utf-16le"} {
+		text := "caf\xe9"
+		if strings.HasSuffix(mimeType, "utf-16le") {
+			text = "c\x00a\x00f\x00\xe9"
+		}
+		assert.Equal(t, "caf\uFFFD", sendResource(text, mimeType).Text, mimeType)
+	}
+
+	// Charsets without a decoder are sent as UTF-8 with invalid sequences replaced, keeping their mime type
+	received = sendResource("caf\xe9", "text/plain; charset=windows-1252")
+	assert.Equal(t, "caf\uFFFD", received.Text)
+	assert.Equal(t, "text/plain; charset=windows-1252", *received.MimeType)
+
+	// Text without a charset is left as is
+	received = sendResource("café", "text/plain")
+	assert.Equal(t, "café", received.Text)
+	assert.Equal(t, "text/plain", *received.MimeType)
+
+	// So are the contents of resource reads and the resources of prompts
+	marshalled, err = json.Marshal(newResourceResponseSent(NewResourceResponse(NewTextEmbeddedResource("file:///notes.txt", latin1, "text/plain; charset=latin1"))))
+	require.NoError(t, err)
+	assert.Contains(t, string(marshalled), `"text":"café naïve"`)
+	marshalled, err = json.Marshal(newPromptResponseSent(NewPromptResponse("notes", NewPromptMessage(NewTextResourceContent("file:///notes.txt", latin1, "text/plain; charset=latin1"), RoleUser))))
+	require.NoError(t, err)
+	assert.Contains(t, string(marshalled), `"text":"café naïve"`)
+
+	// Resources that a client decoded are UTF-8 already, marshaling them again doesn't transcode them a second time
+	var decoded Content
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"resource","resource":{"uri":"file:///notes.txt","mimeType":"text/plain; charset=latin1","text":"café"}}`), &decoded))
+	marshalled, err = json.Marshal(decoded)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(marshalled, &content))
+	assert.Equal(t, "café", content.EmbeddedResource.TextResourceContents.Text)
+}
@@ -0,0 +1,73 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// defaultMaxContentBlocks is the content block limit of servers created without WithMaxContentBlocks
+const defaultMaxContentBlocks = 10000
+
+// WithMaxContentBlocks rejects tool, prompt and resource responses with more than n content blocks with an internal error,
+// instead of sending them to the client. Zero or less removes the limit. The default is 10000.
+func WithMaxContentBlocks(n int) ServerOptions {
+	return func(s *Server) {
+		s.maxContentBlocks = n
+	}
+}
+
+// contentBlocks returns the number of content blocks in a tool, prompt or resource response
+func contentBlocks(result transport.JsonRpcBody) (int, bool) {
+	switch r := result.(type) {
+	case *toolResponseSent:
+		if r.Response != nil && r.Response.raw != nil {
+			return rawContentBlocks(r.Response.raw), true
+		}
+		if r.Response != nil {
+			return len(r.Response.Content), true
+		}
+	case *promptResponseSent:
+		if r.Response != nil {
+			return len(r.Response.Messages), true
+		}
+	case *resourceResponseSent:
+		if r.Response != nil {
+			return len(r.Response.Contents), true
+		}
+	}
+	return 0, false
+}
+
+// rawContentBlocks returns the number of elements of the raw content of a tool response.
+// Raw content that is not a JSON array has none, it is replaced with an error when the response is sent.
+func rawContentBlocks(raw json.RawMessage) int {
+	if validateRawContent(raw) != nil {
+		return 0
+	}
+	var blocks []json.RawMessage
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return 0
+	}
+	return len(blocks)
+}
+
+// withContentLimit wraps handler so that responses with more content blocks than the server's limit are replaced with an error
+func (s *Server) withContentLimit(method string, handler requestHandler) requestHandler {
+	if s.maxContentBlocks <= 0 {
+		return handler
+	}
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		result, err := handler(ctx, request, extra)
+		if err != nil {
+			return result, err
+		}
+		if blocks, ok := contentBlocks(result); ok && blocks > s.maxContentBlocks {
+			return nil, protocol.NewRPCError(protocol.ErrorCodeInternalError, fmt.Sprintf("%s response has %d content blocks, more than the limit of %d", method, blocks, s.maxContentBlocks), nil)
+		}
+		return result, nil
+	}
+}
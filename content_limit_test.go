@@ -0,0 +1,61 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxContentBlocks(t *testing.T) {
+	type args struct {
+		Blocks int `json:"blocks"`
+	}
+	server := NewServer(nil, WithMaxContentBlocks(3))
+	err := server.RegisterTool("blocks", "Returns the requested number of content blocks", func(arguments args) (*ToolResponse, error) {
+		content := make([]*Content, arguments.Blocks)
+		for i := range content {
+			content[i] = NewTextContent("block")
+		}
+		return NewToolResponse(content...), nil
+	})
+	require.NoError(t, err)
+	err = server.RegisterPrompt("long", "Returns too many messages", func(arguments struct{}) (*PromptResponse, error) {
+		message := NewPromptMessage(NewTextContent("message"), RoleUser)
+		return NewPromptResponse("long", message, message, message, message), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	response, err := client.CallTool(context.Background(), "blocks", args{Blocks: 3})
+	require.NoError(t, err)
+	assert.Len(t, response.Content, 3)
+
+	_, err = client.CallTool(context.Background(), "blocks", args{Blocks: 4})
+	assert.ErrorIs(t, err, ErrInternalError)
+	assert.ErrorContains(t, err, "tools/call response has 4 content blocks, more than the limit of 3")
+
+	_, err = client.GetPrompt(context.Background(), "long", struct{}{})
+	assert.ErrorIs(t, err, ErrInternalError)
+
+	// Servers have a generous limit by default
+	assert.Equal(t, defaultMaxContentBlocks, NewServer(nil).maxContentBlocks)
+}
+
+func TestWithMaxContentBlocksRawToolResponse(t *testing.T) {
+	type args struct {
+		Query string `json:"query"`
+	}
+	server := NewServer(nil, WithMaxContentBlocks(2))
+	err := server.RegisterTool("forward", "Forwards upstream content", func(arguments args) (*ToolResponse, error) {
+		return NewRawToolResponse(json.RawMessage(`[{"type":"text","text":"a"},{"type":"text","text":"b"},{"type":"text","text":"c"}]`)), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	_, err = client.CallTool(context.Background(), "forward", args{})
+	assert.ErrorIs(t, err, ErrInternalError)
+	assert.ErrorContains(t, err, "tools/call response has 3 content blocks, more than the limit of 2")
+}
@@ -0,0 +1,61 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// MethodHandler handles a custom JSON-RPC request registered with RegisterMethod.
+// The result is marshalled as the result of the response, an error is sent as a JSON-RPC error.
+type MethodHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// RegisterMethod handles requests for method, which isn't part of MCP, e.g. a vendor extension such as "acme/status".
+// Methods the server handles itself, such as tools/call, take precedence, a handler registered for one of them is never called.
+// Registering a method again replaces its handler.
+func (s *Server) RegisterMethod(method string, handler MethodHandler) error {
+	if method == "" {
+		return errors.New("method must not be empty")
+	}
+	if handler == nil {
+		return errors.Errorf("handler of method %s must not be nil", method)
+	}
+	s.methods.Store(method, handler)
+	return nil
+}
+
+// DeregisterMethod removes the handler of a method registered with RegisterMethod
+func (s *Server) DeregisterMethod(method string) {
+	s.methods.Delete(method)
+}
+
+// withCustomMethods makes the session's protocol call the handlers registered with RegisterMethod for methods it has no handler for.
+// A fallback handler the protocol already had is still called for methods without a registered handler.
+func (s *Server) withCustomMethods(sess *session) {
+	pr := sess.protocol
+	fallback := pr.FallbackRequestHandler
+	handler := s.withRequestLogger(pr, func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		value, ok := s.methods.Load(request.Method)
+		if !ok {
+			if fallback != nil {
+				return fallback(ctx, request)
+			}
+			return nil, protocol.NewRPCError(protocol.ErrorCodeMethodNotFound, fmt.Sprintf("method not found: %s", request.Method), nil)
+		}
+		result, err := value.(MethodHandler)(ctx, request.Params)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			return map[string]interface{}{}, nil
+		}
+		return result, nil
+	})
+	pr.FallbackRequestHandler = func(ctx context.Context, request *transport.BaseJSONRPCRequest) (transport.JsonRpcBody, error) {
+		return withSession(sess, s.withDrain(s.withTimeout(request.Method, handler)))(ctx, request, protocol.RequestHandlerExtra{Context: ctx})
+	}
+}
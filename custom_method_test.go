@@ -0,0 +1,57 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterMethod(t *testing.T) {
+	server := NewServer(nil)
+	err := server.RegisterMethod("custom/echo", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var echo struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(params, &echo); err != nil {
+			return nil, err
+		}
+		return map[string]string{"echo": echo.Message}, nil
+	})
+	require.NoError(t, err)
+	// Built-in methods can't be replaced
+	err = server.RegisterMethod("ping", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return map[string]string{"overridden": "yes"}, nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	// Methods can still be registered once the server is running
+	err = server.RegisterMethod("custom/fail", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return nil, protocol.NewRPCError(protocol.ErrorCodeInvalidParams, "bad input", nil)
+	})
+	require.NoError(t, err)
+
+	var echo map[string]string
+	err = client.protocol.RequestInto(context.Background(), "custom/echo", map[string]string{"message": "hello"}, &echo, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"echo": "hello"}, echo)
+
+	var pong map[string]string
+	err = client.protocol.RequestInto(context.Background(), "ping", nil, &pong, nil)
+	require.NoError(t, err)
+	assert.Empty(t, pong)
+
+	_, err = client.protocol.Request(context.Background(), "custom/fail", nil, nil)
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+
+	_, err = client.protocol.Request(context.Background(), "custom/unknown", nil, nil)
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, protocol.ErrorCodeMethodNotFound, rpcErr.Code)
+}
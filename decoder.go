@@ -0,0 +1,63 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Decoder decodes the arguments of tool calls and prompt requests into the handler's argument type.
+// Arguments are decoded with json.Unmarshal unless the server is created with WithDecoder.
+type Decoder interface {
+	Decode(data []byte, v any) error
+}
+
+// DecoderFunc adapts a function with the signature of json.Unmarshal to a Decoder
+type DecoderFunc func(data []byte, v any) error
+
+func (f DecoderFunc) Decode(data []byte, v any) error {
+	return f(data, v)
+}
+
+// WithDecoder decodes tool and prompt arguments with decoder instead of json.Unmarshal,
+// e.g. for arguments encoded with MessagePack or to decode numbers differently.
+// Tool arguments are still validated against the tool's input schema, after being decoded.
+func WithDecoder(decoder Decoder) ServerOptions {
+	return func(s *Server) {
+		s.decoder = decoder
+	}
+}
+
+// WithUseNumber decodes numbers in tool and prompt arguments that are not decoded into a numeric type, e.g. the values
+// of RegisterDynamicTool arguments or fields of type any, as json.Number rather than float64.
+// This keeps integers beyond 2^53, such as big ids or amounts, exact. It has no effect with WithDecoder.
+func WithUseNumber() ServerOptions {
+	return func(s *Server) {
+		s.useNumber = true
+	}
+}
+
+// decodeArguments decodes arguments into v with the server's decoder
+func (s *Server) decodeArguments(arguments []byte, v any) error {
+	if s.decoder != nil {
+		return s.decoder.Decode(arguments, v)
+	}
+	if s.useNumber {
+		decoder := json.NewDecoder(bytes.NewReader(arguments))
+		decoder.UseNumber()
+		return decoder.Decode(v)
+	}
+	return json.Unmarshal(arguments, v)
+}
+
+// argumentsForValidation returns the arguments as JSON for validating them against a tool's input schema.
+// With a custom decoder the arguments are decoded first, so that the decoded value is what gets validated.
+func (s *Server) argumentsForValidation(arguments json.RawMessage) (json.RawMessage, error) {
+	if s.decoder == nil || len(arguments) == 0 {
+		return arguments, nil
+	}
+	var value any
+	if err := s.decoder.Decode(arguments, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,134 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// base64Decoder decodes arguments sent as a base64 encoded JSON string, standing in for a binary encoding
+func base64Decoder(calls *int) Decoder {
+	return DecoderFunc(func(data []byte, v any) error {
+		*calls++
+		var encoded string
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return err
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(decoded, v)
+	})
+}
+
+func encodeArguments(arguments string) string {
+	return `"` + base64.StdEncoding.EncodeToString([]byte(arguments)) + `"`
+}
+
+func TestWithDecoder(t *testing.T) {
+	type weatherArgs struct {
+		City string `json:"city" jsonschema:"required"`
+	}
+	type greetingArgs struct {
+		Name string `json:"name" jsonschema:"required"`
+	}
+	var calls int
+	server := NewServer(testingutils.NewMockTransport(), WithDecoder(base64Decoder(&calls)))
+	var receivedCity string
+	err := server.RegisterTool("weather", "Get the weather", func(args weatherArgs) (*ToolResponse, error) {
+		receivedCity = args.City
+		return NewToolResponse(NewTextContent("sunny")), nil
+	})
+	require.NoError(t, err)
+	err = server.RegisterPrompt("greeting", "Greet someone", func(args greetingArgs) (*PromptResponse, error) {
+		return NewPromptResponse("greeting", NewPromptMessage(NewTextContent("hello "+args.Name), RoleUser)), nil
+	})
+	require.NoError(t, err)
+
+	resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":` + encodeArguments(`{"city":"London"}`) + `}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Nil(t, resp.(*toolResponseSent).Error)
+	assert.Equal(t, "London", receivedCity)
+
+	// The decoded arguments are validated against the schema
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":` + encodeArguments(`{}`) + `}`),
+	}, protocol.RequestHandlerExtra{})
+	var rpcErr *protocol.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "city")
+
+	// Arguments the decoder can't decode are rejected before the handler is called
+	receivedCity = ""
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":{"city":"London"}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, protocol.ErrorCodeInvalidParams, rpcErr.Code)
+	assert.Empty(t, receivedCity)
+
+	// Prompt arguments are decoded too
+	promptResp, err := server.handlePromptCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"greeting","arguments":` + encodeArguments(`{"name":"Ada"}`) + `}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	sent := promptResp.(*promptResponseSent)
+	require.Nil(t, sent.Error)
+	assert.Equal(t, "hello Ada", sent.Response.Messages[0].Content.TextContent.Text)
+
+	assert.Equal(t, 5, calls)
+}
+
+func TestWithUseNumber(t *testing.T) {
+	// 2^53+1, the smallest integer a float64 can't represent
+	const bigInt = "9007199254740993"
+	type transferArgs struct {
+		Amount   int64       `json:"amount"`
+		Account  json.Number `json:"account"`
+		Metadata any         `json:"metadata"`
+	}
+	server := NewServer(testingutils.NewMockTransport(), WithUseNumber())
+	var received transferArgs
+	require.NoError(t, server.RegisterTool("transfer", "Transfer an amount", func(arguments transferArgs) (*ToolResponse, error) {
+		received = arguments
+		return NewToolResponse(NewTextContent("ok")), nil
+	}))
+	var dynamicArgs map[string]any
+	require.NoError(t, server.RegisterDynamicTool("lookup", "Look up an account", json.RawMessage(`{"type":"object","properties":{"id":{"type":"integer"}}}`), func(args map[string]any) (*ToolResponse, error) {
+		dynamicArgs = args
+		return NewToolResponse(NewTextContent("ok")), nil
+	}))
+
+	call := func(params string) {
+		result, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{Params: json.RawMessage(params)}, protocol.RequestHandlerExtra{})
+		require.NoError(t, err)
+		require.NoError(t, result.(*toolResponseSent).Error)
+	}
+	call(`{"name":"transfer","arguments":{"amount":` + bigInt + `,"account":` + bigInt + `,"metadata":{"reference":` + bigInt + `}}}`)
+	assert.Equal(t, int64(9007199254740993), received.Amount)
+	assert.Equal(t, json.Number(bigInt), received.Account)
+	assert.Equal(t, map[string]any{"reference": json.Number(bigInt)}, received.Metadata)
+
+	call(`{"name":"lookup","arguments":{"id":` + bigInt + `}}`)
+	assert.Equal(t, json.Number(bigInt), dynamicArgs["id"])
+
+	// Without the option, untyped numbers are float64 and lose precision
+	server = NewServer(testingutils.NewMockTransport())
+	require.NoError(t, server.RegisterTool("transfer", "Transfer an amount", func(arguments transferArgs) (*ToolResponse, error) {
+		received = arguments
+		return NewToolResponse(NewTextContent("ok")), nil
+	}))
+	call(`{"name":"transfer","arguments":{"metadata":{"reference":` + bigInt + `}}}`)
+	assert.Equal(t, map[string]any{"reference": float64(9007199254740992)}, received.Metadata)
+}
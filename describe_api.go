@@ -0,0 +1,38 @@
+package mcp_golang
+
+// MethodType is whether a JSON-RPC method is called as a request or sent as a notification
+type MethodType string
+
+const (
+	MethodTypeRequest      MethodType = "request"
+	MethodTypeNotification MethodType = "notification"
+)
+
+// MethodDescription describes a single JSON-RPC method that the server handles
+type MethodDescription struct {
+	// The name of the method, e.g. "tools/call".
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// Whether the method is a request or a notification.
+	Type MethodType `json:"type" yaml:"type" mapstructure:"type"`
+}
+
+// The server's response to a server/describe request from the client.
+// The server only handles server/describe when created with WithDescribeEndpoint.
+type DescribeResponse struct {
+	// Methods is every method the server handles, ordered by name.
+	Methods []MethodDescription `json:"methods" yaml:"methods" mapstructure:"methods"`
+}
+
+// ServerDescription is a snapshot of every tool, prompt and resource registered on a server, returned by Server.Describe.
+// It serializes to JSON, e.g. for generating client code or documentation offline.
+type ServerDescription struct {
+	// Tools ordered by name.
+	Tools []ToolRetType `json:"tools" yaml:"tools" mapstructure:"tools"`
+
+	// Prompts ordered by name.
+	Prompts []*PromptSchema `json:"prompts" yaml:"prompts" mapstructure:"prompts"`
+
+	// Resources ordered by uri.
+	Resources []*ResourceSchema `json:"resources" yaml:"resources" mapstructure:"resources"`
+}
@@ -0,0 +1,29 @@
+package mcp_golang
+
+import (
+	"context"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// Drain makes the server reject new requests with a "server draining" error (code -32000, ErrServerError for clients),
+// while requests that are already being handled run to completion. Use it to stop taking work before shutting down.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+// Undrain makes the server accept new requests again after Drain
+func (s *Server) Undrain() {
+	s.draining.Store(false)
+}
+
+// withDrain wraps handler so that it rejects requests while the server is draining
+func (s *Server) withDrain(handler requestHandler) requestHandler {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		if s.draining.Load() {
+			return nil, protocol.NewRPCError(protocol.ErrorCodeServerError, "server draining", nil)
+		}
+		return handler(ctx, request, extra)
+	}
+}
@@ -0,0 +1,54 @@
+package mcp_golang
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDrain(t *testing.T) {
+	type args struct{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := NewServer(nil)
+	require.NoError(t, server.RegisterTool("slow", "Waits until released", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		close(started)
+		<-release
+		return NewToolResponse(NewTextContent("done")), nil
+	}))
+	require.NoError(t, server.RegisterTool("fast", "Returns immediately", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent("done")), nil
+	}))
+	client := newInProcessClient(t, server)
+
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := client.CallTool(context.Background(), "slow", args{})
+		inFlight <- err
+	}()
+	<-started
+
+	server.Drain()
+	_, err := client.CallTool(context.Background(), "fast", args{})
+	assert.ErrorIs(t, err, ErrServerError)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32000, rpcErr.Code)
+	assert.Equal(t, "server draining", rpcErr.Message)
+
+	// The call that was already running completes
+	close(release)
+	select {
+	case err := <-inFlight:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("In-flight call did not complete")
+	}
+
+	server.Undrain()
+	_, err = client.CallTool(context.Background(), "fast", args{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,90 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// ErrElicitationNotSupported is returned by Server.Elicit when the client didn't advertise the elicitation capability
+var ErrElicitationNotSupported = errors.New("client does not support elicitation")
+
+// ElicitationAction is how the user answered an elicitation request
+type ElicitationAction string
+
+const (
+	// The user submitted the requested input
+	ElicitationActionAccept ElicitationAction = "accept"
+	// The user explicitly declined to provide the input
+	ElicitationActionDecline ElicitationAction = "decline"
+	// The user dismissed the request without choosing
+	ElicitationActionCancel ElicitationAction = "cancel"
+)
+
+// ElicitationRequest is an elicitation/create request from the server, asking the user for structured input
+type ElicitationRequest struct {
+	// The message to show the user.
+	Message string `json:"message" yaml:"message" mapstructure:"message"`
+
+	// A JSON schema of the object the user is asked to provide.
+	RequestedSchema json.RawMessage `json:"requestedSchema" yaml:"requestedSchema" mapstructure:"requestedSchema"`
+}
+
+// ElicitationResponse is the client's answer to an elicitation/create request
+type ElicitationResponse struct {
+	// How the user answered the request.
+	Action ElicitationAction `json:"action" yaml:"action" mapstructure:"action"`
+
+	// The input the user submitted, matching the requested schema. Only set when the action is accept.
+	Content json.RawMessage `json:"content,omitempty" yaml:"content,omitempty" mapstructure:"content,omitempty"`
+}
+
+// Elicit asks the user for input matching schema through the client, showing them message, and returns the client's
+// result, an ElicitationResponse as JSON. It is sent to the client that made the request ctx belongs to, or to the client
+// of the server's main transport outside of requests, and fails with ErrElicitationNotSupported if that client didn't
+// advertise the elicitation capability when it initialized.
+func (s *Server) Elicit(ctx context.Context, schema json.RawMessage, message string) (json.RawMessage, error) {
+	if !s.isRunning.Load() {
+		return nil, errors.New("server is not running")
+	}
+	sess := s.session(ctx)
+	if !sess.clientElicitation.Load() {
+		return nil, ErrElicitationNotSupported
+	}
+	var result json.RawMessage
+	err := sess.protocol.RequestInto(ctx, "elicitation/create", ElicitationRequest{
+		Message:         message,
+		RequestedSchema: schema,
+	}, &result, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to elicit input")
+	}
+	return result, nil
+}
+
+// SetElicitationHandler handles elicitation/create requests from the server with handler, which should ask the user for the requested input.
+// It must be set before Initialize, so that the client advertises the elicitation capability to the server.
+func (c *Client) SetElicitationHandler(handler func(ctx context.Context, request ElicitationRequest) (*ElicitationResponse, error)) {
+	c.elicitationEnabled = handler != nil
+	if handler == nil {
+		c.protocol.RemoveRequestHandler("elicitation/create")
+		return
+	}
+	c.protocol.SetRequestHandler("elicitation/create", func(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		var params ElicitationRequest
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal elicitation request"))
+		}
+		response, err := handler(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if response == nil {
+			return nil, errors.New("elicitation handler returned no response")
+		}
+		return response, nil
+	})
+}
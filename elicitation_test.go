@@ -0,0 +1,89 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElicitation(t *testing.T) {
+	type bookingArgs struct {
+		Restaurant string `json:"restaurant"`
+	}
+	schema := json.RawMessage(`{"type":"object","properties":{"guests":{"type":"number"}},"required":["guests"]}`)
+
+	newServer := func() *Server {
+		server := NewServer(nil)
+		err := server.RegisterTool("book", "Book a table", func(ctx context.Context, args bookingArgs) (*ToolResponse, error) {
+			result, err := server.Elicit(ctx, schema, "How many guests for "+args.Restaurant+"?")
+			if err != nil {
+				return nil, err
+			}
+			var response ElicitationResponse
+			if err := json.Unmarshal(result, &response); err != nil {
+				return nil, err
+			}
+			if response.Action != ElicitationActionAccept {
+				return NewToolResponse(NewTextContent("booking " + string(response.Action))), nil
+			}
+			return NewToolResponse(NewTextContent("booked for " + string(response.Content))), nil
+		})
+		require.NoError(t, err)
+		return server
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		server := newServer()
+		serverTransport, clientTransport := newPipeTransports(t)
+		server.transport = serverTransport
+		require.NoError(t, server.Serve())
+
+		var received ElicitationRequest
+		client := NewClient(clientTransport)
+		client.SetElicitationHandler(func(ctx context.Context, request ElicitationRequest) (*ElicitationResponse, error) {
+			received = request
+			return &ElicitationResponse{
+				Action:  ElicitationActionAccept,
+				Content: json.RawMessage(`{"guests":4}`),
+			}, nil
+		})
+		_, err := client.Initialize(context.Background())
+		require.NoError(t, err)
+
+		response, err := client.CallTool(context.Background(), "book", bookingArgs{Restaurant: "Luigi's"})
+		require.NoError(t, err)
+		require.Len(t, response.Content, 1)
+		assert.Equal(t, `booked for {"guests":4}`, response.Content[0].TextContent.Text)
+		assert.Equal(t, "How many guests for Luigi's?", received.Message)
+		assert.JSONEq(t, string(schema), string(received.RequestedSchema))
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		server := newServer()
+		serverTransport, clientTransport := newPipeTransports(t)
+		server.transport = serverTransport
+		require.NoError(t, server.Serve())
+
+		client := NewClient(clientTransport)
+		client.SetElicitationHandler(func(ctx context.Context, request ElicitationRequest) (*ElicitationResponse, error) {
+			return &ElicitationResponse{Action: ElicitationActionDecline}, nil
+		})
+		_, err := client.Initialize(context.Background())
+		require.NoError(t, err)
+
+		response, err := client.CallTool(context.Background(), "book", bookingArgs{Restaurant: "Luigi's"})
+		require.NoError(t, err)
+		assert.Equal(t, "booking decline", response.Content[0].TextContent.Text)
+	})
+
+	t.Run("client without the capability", func(t *testing.T) {
+		server := newServer()
+		newInProcessClient(t, server)
+
+		_, err := server.Elicit(context.Background(), schema, "How many guests?")
+		assert.ErrorIs(t, err, ErrElicitationNotSupported)
+	})
+}
@@ -0,0 +1,44 @@
+package mcp_golang
+
+import (
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// RPCError is a JSON-RPC error returned by the other side, or raised locally for timeouts.
+// Use errors.As to read its code and data:
+//
+//	var rpcErr *mcp_golang.RPCError
+//	if errors.As(err, &rpcErr) {
+//		log.Printf("code %d: %s", rpcErr.Code, rpcErr.Data)
+//	}
+type RPCError = protocol.RPCError
+
+// Sentinel errors that can be checked with errors.Is on errors returned by the client
+var (
+	ErrParseError     = protocol.ErrParseError
+	ErrInvalidRequest = protocol.ErrInvalidRequest
+	ErrMethodNotFound = protocol.ErrMethodNotFound
+	ErrInvalidParams  = protocol.ErrInvalidParams
+	ErrInternalError  = protocol.ErrInternalError
+	// ErrServerError is the generic error the server reports when a handler fails
+	ErrServerError = protocol.ErrServerError
+	// ErrRequestTimeout is returned when the server doesn't answer within the request timeout
+	ErrRequestTimeout = protocol.ErrRequestTimeout
+	// ErrResourceNotFound is returned when reading a resource the server doesn't know
+	ErrResourceNotFound = protocol.ErrResourceNotFound
+	// ErrConnectionClosed is returned for requests that were pending when the connection closed
+	ErrConnectionClosed = protocol.ErrConnectionClosed
+	// ErrTransport is returned when a request could not be sent
+	ErrTransport = protocol.ErrTransport
+
+	ErrClientNotInitialized     = errors.New("client not initialized")
+	ErrClientAlreadyInitialized = errors.New("client already initialized")
+
+	// ErrToolsNotSupported is returned when using tools of a server that didn't advertise the tools capability
+	ErrToolsNotSupported = errors.New("server does not support tools")
+	// ErrPromptsNotSupported is returned when using prompts of a server that didn't advertise the prompts capability
+	ErrPromptsNotSupported = errors.New("server does not support prompts")
+	// ErrResourcesNotSupported is returned when using resources of a server that didn't advertise the resources capability
+	ErrResourcesNotSupported = errors.New("server does not support resources")
+)
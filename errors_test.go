@@ -0,0 +1,68 @@
+package mcp_golang
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientErrors(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	server := NewServer(nil)
+	// Registered so that the server advertises tools and resources
+	require.NoError(t, server.RegisterTool("echo", "Echo a message", func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}))
+	require.NoError(t, server.RegisterResource("file:///known.txt", "known", "A resource", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///known.txt", "known", "text/plain")), nil
+	}))
+	client := newInProcessClient(t, server)
+
+	t.Run("method not found", func(t *testing.T) {
+		// The describe endpoint is not enabled on this server
+		_, err := client.Describe(context.Background())
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrMethodNotFound), "expected ErrMethodNotFound, got %v", err)
+
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr))
+		assert.Equal(t, -32601, rpcErr.Code)
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		_, err := client.CallTool(context.Background(), "missing", map[string]string{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrInvalidParams), "expected ErrInvalidParams, got %v", err)
+		assert.False(t, errors.Is(err, ErrMethodNotFound))
+	})
+
+	t.Run("unknown resource", func(t *testing.T) {
+		_, err := client.ReadResource(context.Background(), "file:///missing.txt")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrResourceNotFound), "expected ErrResourceNotFound, got %v", err)
+
+		var rpcErr *RPCError
+		require.True(t, errors.As(err, &rpcErr))
+		assert.Equal(t, map[string]interface{}{"uri": "file:///missing.txt"}, rpcErr.Data)
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		client := NewClient(testingutils.NewMockTransport(), WithInitializeTimeout(10*time.Millisecond))
+		_, err := client.Initialize(context.Background())
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrRequestTimeout), "expected ErrRequestTimeout, got %v", err)
+	})
+
+	t.Run("not initialized", func(t *testing.T) {
+		client := NewClient(testingutils.NewMockTransport())
+		_, err := client.ListTools(context.Background(), nil)
+		assert.True(t, errors.Is(err, ErrClientNotInitialized), "expected ErrClientNotInitialized, got %v", err)
+	})
+}
@@ -25,9 +25,9 @@ func main() {
 
 	// Register a simple tool
 	err := server.RegisterTool("time", "Returns the current time in the specified format", func(ctx context.Context, args TimeArgs) (*mcp_golang.ToolResponse, error) {
-		ginCtx, ok := ctx.Value("ginContext").(*gin.Context)
+		ginCtx, ok := http.GinContextFromContext(ctx)
 		if !ok {
-			return nil, fmt.Errorf("ginContext not found in context")
+			return nil, fmt.Errorf("gin context not found in context")
 		}
 		userAgent := ginCtx.GetHeader("User-Agent")
 		log.Printf("Request from User-Agent: %s", userAgent)
@@ -78,12 +78,12 @@ func main() {
 	for i, name := range resourceNames {
 		content := fmt.Sprintf("This is resource %d", i+1)
 		err = server.RegisterResource(
-			name,
+			"file:///"+name,
 			fmt.Sprintf("Resource %d", i+1),
 			fmt.Sprintf("Description for resource %d", i+1),
 			"text/plain",
 			func() (*mcp_golang.ResourceResponse, error) {
-				return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(name, content, "text/plain")), nil
+				return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource("file:///"+name, content, "text/plain")), nil
 			},
 		)
 		if err != nil {
@@ -33,3 +33,13 @@ func (m *SyncMap[K, V]) Range(f func(key K, value V) bool) {
 func (m *SyncMap[K, V]) Store(key K, value V) {
 	m.m.Store(key, value)
 }
+
+// Empty reports whether the map has no entries
+func (m *SyncMap[K, V]) Empty() bool {
+	empty := true
+	m.m.Range(func(key, value any) bool {
+		empty = false
+		return false
+	})
+	return empty
+}
@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// JSON-RPC error codes, plus the codes MCP adds for timeouts and missing resources
+const (
+	ErrorCodeParseError       = -32700
+	ErrorCodeInvalidRequest   = -32600
+	ErrorCodeMethodNotFound   = -32601
+	ErrorCodeInvalidParams    = -32602
+	ErrorCodeInternalError    = -32603
+	ErrorCodeServerError      = -32000
+	ErrorCodeRequestTimeout   = -32001
+	ErrorCodeResourceNotFound = -32002
+)
+
+// Sentinel errors for the JSON-RPC error codes. An RPCError with a known code unwraps to one of these.
+var (
+	ErrParseError       = errors.New("parse error")
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrMethodNotFound   = errors.New("method not found")
+	ErrInvalidParams    = errors.New("invalid params")
+	ErrInternalError    = errors.New("internal error")
+	ErrServerError      = errors.New("server error")
+	ErrRequestTimeout   = errors.New("request timeout")
+	ErrResourceNotFound = errors.New("resource not found")
+
+	// ErrConnectionClosed is returned for requests that were pending when the connection closed
+	ErrConnectionClosed = errors.New("connection closed")
+	// ErrTransport is returned when a message could not be handed to the transport
+	ErrTransport = errors.New("transport error")
+)
+
+// RPCError is a JSON-RPC error, either received from the remote side or raised locally, e.g. on timeout.
+// Use errors.Is with the sentinel errors to check the kind of error, or errors.As to read the code and data.
+type RPCError struct {
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the sentinel error for the code, or nil for codes without one
+func (e *RPCError) Unwrap() error {
+	switch e.Code {
+	case ErrorCodeParseError:
+		return ErrParseError
+	case ErrorCodeInvalidRequest:
+		return ErrInvalidRequest
+	case ErrorCodeMethodNotFound:
+		return ErrMethodNotFound
+	case ErrorCodeInvalidParams:
+		return ErrInvalidParams
+	case ErrorCodeInternalError:
+		return ErrInternalError
+	case ErrorCodeServerError:
+		return ErrServerError
+	case ErrorCodeRequestTimeout:
+		return ErrRequestTimeout
+	case ErrorCodeResourceNotFound:
+		return ErrResourceNotFound
+	default:
+		return nil
+	}
+}
+
+// NewRPCError creates an RPCError. Request handlers can return one to answer with a specific code.
+func NewRPCError(code int, message string, data interface{}) *RPCError {
+	return &RPCError{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}
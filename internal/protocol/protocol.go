@@ -68,6 +68,7 @@ package protocol
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -104,6 +105,10 @@ type RequestOptions struct {
 	Timeout time.Duration
 }
 
+// Interceptor inspects or modifies a message as it passes through the protocol.
+// Returning an error aborts sending or dispatching the message.
+type Interceptor func(message *transport.BaseJsonRpcMessage) error
+
 // RequestHandlerExtra contains extra data given to request handlers
 type RequestHandlerExtra struct {
 	// Context used to communicate if the request was cancelled from the sender's side
@@ -129,6 +134,10 @@ type Protocol struct {
 	responseHandlers map[transport.RequestId]chan *responseEnvelope
 	// Maps message ID to progress handler
 	progressHandlers map[transport.RequestId]ProgressCallback
+	// Run in order on every message just before it is sent
+	outboundInterceptors []Interceptor
+	// Run in order on every message just after it is received
+	inboundInterceptors []Interceptor
 
 	// Callback for when the connection is closed for any reason
 	OnClose func()
@@ -138,6 +147,8 @@ type Protocol struct {
 	FallbackRequestHandler func(ctx context.Context, request *transport.BaseJSONRPCRequest) (transport.JsonRpcBody, error)
 	// Handler to invoke for any notification types that do not have their own handler installed
 	FallbackNotificationHandler func(notification *transport.BaseJSONRPCNotification) error
+	// Receives diagnostic messages, such as errors returned by request handlers. They are printed to stderr if nil
+	Logf func(format string, v ...any)
 }
 
 type responseEnvelope struct {
@@ -163,8 +174,76 @@ func NewProtocol(options *ProtocolOptions) *Protocol {
 	return p
 }
 
+// WithOutboundInterceptor adds an interceptor that runs on every message just before it is handed to the transport.
+// If it returns an error, the message is not sent and the error is returned to the sender.
+func (p *Protocol) WithOutboundInterceptor(interceptor Interceptor) *Protocol {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outboundInterceptors = append(p.outboundInterceptors, interceptor)
+	return p
+}
+
+// WithInboundInterceptor adds an interceptor that runs on every message received from the transport before it is dispatched.
+// If it returns an error, the message is dropped. A rejected request is answered with that error.
+func (p *Protocol) WithInboundInterceptor(interceptor Interceptor) *Protocol {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inboundInterceptors = append(p.inboundInterceptors, interceptor)
+	return p
+}
+
+// intercept runs the given interceptors on the message, stopping at the first error
+func (p *Protocol) intercept(interceptors []Interceptor, message *transport.BaseJsonRpcMessage) error {
+	for _, interceptor := range interceptors {
+		if err := interceptor(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send runs the outbound interceptors on the message and hands it to the transport
+func (p *Protocol) send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	p.mu.RLock()
+	interceptors := p.outboundInterceptors
+	p.mu.RUnlock()
+	if err := p.intercept(interceptors, message); err != nil {
+		return fmt.Errorf("outbound interceptor rejected message: %w", err)
+	}
+	setJsonrpcVersion(message)
+	return p.transport.Send(ctx, message)
+}
+
+// jsonrpcVersion is the JSON-RPC version every message is sent with
+const jsonrpcVersion = "2.0"
+
+// setJsonrpcVersion sets the jsonrpc field of message, and of every message of a batch, so that no message is sent
+// without it, whoever built it
+func setJsonrpcVersion(message *transport.BaseJsonRpcMessage) {
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCRequestType:
+		message.JsonRpcRequest.Jsonrpc = jsonrpcVersion
+	case transport.BaseMessageTypeJSONRPCNotificationType:
+		message.JsonRpcNotification.Jsonrpc = jsonrpcVersion
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		message.JsonRpcResponse.Jsonrpc = jsonrpcVersion
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		message.JsonRpcError.Jsonrpc = jsonrpcVersion
+	case transport.BaseMessageTypeJSONRPCBatchType:
+		for _, m := range message.JsonRpcBatch {
+			setJsonrpcVersion(m)
+		}
+	}
+}
+
 // Connect attaches to the given transport, starts it, and starts listening for messages
 func (p *Protocol) Connect(tr transport.Transport) error {
+	return p.ConnectWithContext(context.Background(), tr)
+}
+
+// ConnectWithContext attaches to the given transport like Connect, and starts it with ctx
+// so that the transport's goroutines stop when ctx is cancelled
+func (p *Protocol) ConnectWithContext(ctx context.Context, tr transport.Transport) error {
 	p.transport = tr
 
 	tr.SetCloseHandler(func() {
@@ -176,6 +255,19 @@ func (p *Protocol) Connect(tr transport.Transport) error {
 	})
 
 	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		p.mu.RLock()
+		interceptors := p.inboundInterceptors
+		p.mu.RUnlock()
+		if err := p.intercept(interceptors, message); err != nil {
+			err = fmt.Errorf("inbound interceptor rejected message: %w", err)
+			if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+				p.sendErrorResponse(message.JsonRpcRequest.Id, err)
+				return
+			}
+			p.handleError(err)
+			return
+		}
+
 		switch m := message.Type; {
 		case m == transport.BaseMessageTypeJSONRPCRequestType:
 			p.handleRequest(ctx, message.JsonRpcRequest)
@@ -188,7 +280,7 @@ func (p *Protocol) Connect(tr transport.Transport) error {
 		}
 	})
 
-	return tr.Start(context.Background())
+	return tr.Start(ctx)
 }
 
 func (p *Protocol) handleClose() {
@@ -207,7 +299,7 @@ func (p *Protocol) handleClose() {
 
 	// Close all response channels with error
 	for id, ch := range p.responseHandlers {
-		ch <- &responseEnvelope{err: fmt.Errorf("connection closed")}
+		ch <- &responseEnvelope{err: ErrConnectionClosed}
 		close(ch)
 		delete(p.responseHandlers, id)
 	}
@@ -219,6 +311,15 @@ func (p *Protocol) handleClose() {
 	}
 }
 
+// logf writes a diagnostic message to Logf, or to stderr if it is not set
+func (p *Protocol) logf(format string, v ...any) {
+	if p.Logf != nil {
+		p.Logf(format, v...)
+		return
+	}
+	println(fmt.Sprintf(format, v...))
+}
+
 func (p *Protocol) handleError(err error) {
 	if p.OnError != nil {
 		p.OnError(err)
@@ -252,12 +353,17 @@ func (p *Protocol) handleRequest(ctx context.Context, request *transport.BaseJSO
 			if p.FallbackRequestHandler != nil {
 				return p.FallbackRequestHandler(ctx, req)
 			}
-			println("no handler for method and no default handler:", req.Method)
-			return nil, fmt.Errorf("method not found: %s", req.Method)
+			p.logf("no handler for method and no default handler: %s", req.Method)
+			return nil, NewRPCError(ErrorCodeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method), nil)
 		}
 	}
 	p.mu.RUnlock()
 
+	if _, ok := transport.RequestIDFromContext(ctx); !ok {
+		ctx = transport.ContextWithRequestID(ctx, request.Id)
+	}
+	queue := &afterResponseQueue{}
+	ctx = context.WithValue(ctx, afterResponseKey{}, queue)
 	ctx, cancel := context.WithCancel(ctx)
 	p.mu.Lock()
 	p.requestCancellers[request.Id] = cancel
@@ -273,15 +379,15 @@ func (p *Protocol) handleRequest(ctx context.Context, request *transport.BaseJSO
 
 		result, err := handler(ctx, request, RequestHandlerExtra{Context: ctx})
 		if err != nil {
-			println("error:", err.Error())
-			p.sendErrorResponse(request.Id, err)
+			p.logf("error: %s", err.Error())
+			p.sendResponse(ctx, newErrorResponse(request.Id, err), queue.take())
 			return
 		}
 
 		jsonResult, err := json.Marshal(result)
 		if err != nil {
-			println("error:", err.Error())
-			p.sendErrorResponse(request.Id, fmt.Errorf("failed to marshal result: %w", err))
+			p.logf("error: %s", err.Error())
+			p.sendResponse(ctx, newErrorResponse(request.Id, fmt.Errorf("failed to marshal result: %w", err)), queue.take())
 			return
 		}
 		response := &transport.BaseJSONRPCResponse{
@@ -289,12 +395,81 @@ func (p *Protocol) handleRequest(ctx context.Context, request *transport.BaseJSO
 			Id:      request.Id,
 			Result:  jsonResult,
 		}
+		if statusCoder, ok := result.(transport.StatusCoder); ok {
+			response.StatusCode = statusCoder.StatusCode()
+		}
+		if eTagger, ok := result.(transport.ETagger); ok {
+			if etag, cacheable := eTagger.ETag(); cacheable {
+				response.ETag = &etag
+			}
+		}
+
+		p.sendResponse(ctx, transport.NewBaseMessageResponse(response), queue.take())
+	}()
+}
 
-		if err := p.transport.Send(ctx, transport.NewBaseMessageResponse(response)); err != nil {
-			println("error:", err.Error())
+// sendResponse sends the response to a request, followed by the notifications its handler queued with NotifyAfterResponse.
+// Transports that can only send messages as part of a response get them all in one batch.
+func (p *Protocol) sendResponse(ctx context.Context, response *transport.BaseJsonRpcMessage, notifications []*transport.BaseJsonRpcMessage) {
+	if batcher, ok := p.transport.(transport.ResponseBatchTransport); ok && len(notifications) > 0 && batcher.BatchesWithResponse() {
+		if err := p.send(ctx, transport.NewBaseMessageBatch(append([]*transport.BaseJsonRpcMessage{response}, notifications...)...)); err != nil {
+			p.logf("error: %s", err.Error())
 			p.handleError(fmt.Errorf("failed to send response: %w", err))
 		}
-	}()
+		return
+	}
+
+	if err := p.send(ctx, response); err != nil {
+		p.logf("error: %s", err.Error())
+		p.handleError(fmt.Errorf("failed to send response: %w", err))
+	}
+	for _, notification := range notifications {
+		if err := p.send(ctx, notification); err != nil {
+			p.handleError(fmt.Errorf("failed to send notification after response: %w", err))
+		}
+	}
+}
+
+type afterResponseKey struct{}
+
+// afterResponseQueue holds the notifications a request handler wants sent once its response is
+type afterResponseQueue struct {
+	mu            sync.Mutex
+	notifications []*transport.BaseJsonRpcMessage
+	// Set once the response is being sent, after which nothing can be queued
+	taken bool
+}
+
+// take returns the queued notifications and closes the queue
+func (q *afterResponseQueue) take() []*transport.BaseJsonRpcMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.taken = true
+	return q.notifications
+}
+
+// NotifyAfterResponse queues a notification to be sent once the response to the request ctx belongs to is sent.
+// It returns an error if ctx is not the context of a request handler, or if the response was already sent.
+func NotifyAfterResponse(ctx context.Context, method string, params interface{}) error {
+	queue, ok := ctx.Value(afterResponseKey{}).(*afterResponseQueue)
+	if !ok {
+		return errors.New("not handling a request, there is no response to send the notification after")
+	}
+	marshalled, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification params: %w", err)
+	}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if queue.taken {
+		return errors.New("the response was already sent")
+	}
+	queue.notifications = append(queue.notifications, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  marshalled,
+	}))
+	return nil
 }
 
 func (p *Protocol) handleProgressNotification(notification *transport.BaseJSONRPCNotification) error {
@@ -350,7 +525,7 @@ func (p *Protocol) handleResponse(response *transport.BaseJSONRPCResponse, errRe
 
 	if errResp != nil {
 		id = errResp.Id
-		err = fmt.Errorf("RPC error %d: %s", errResp.Error.Code, errResp.Error.Message)
+		err = NewRPCError(errResp.Error.Code, errResp.Error.Message, errResp.Error.Data)
 	} else {
 		// Parse the response
 		result = response.Result
@@ -442,8 +617,8 @@ func (p *Protocol) Request(ctx context.Context, method string, params interface{
 		Id:      id,
 	}
 
-	if err := p.transport.Send(ctx, transport.NewBaseMessageRequest(request)); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	if err := p.send(ctx, transport.NewBaseMessageRequest(request)); err != nil {
+		return nil, fmt.Errorf("%w: failed to send request: %w", ErrTransport, err)
 	}
 
 	select {
@@ -457,10 +632,31 @@ func (p *Protocol) Request(ctx context.Context, method string, params interface{
 		return nil, opts.Context.Err()
 	case <-time.After(opts.Timeout):
 		p.sendCancelNotification(id, "request timeout")
-		return nil, fmt.Errorf("request timeout after %v", opts.Timeout)
+		return nil, NewRPCError(ErrorCodeRequestTimeout, fmt.Sprintf("request timeout after %v", opts.Timeout), nil)
 	}
 }
 
+// RequestInto sends a request, waits for a response and unmarshals the result into out.
+// If out is nil the result is discarded.
+func (p *Protocol) RequestInto(ctx context.Context, method string, params interface{}, out interface{}, opts *RequestOptions) error {
+	response, err := p.Request(ctx, method, params, opts)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+
+	responseBytes, ok := response.(json.RawMessage)
+	if !ok {
+		return fmt.Errorf("invalid response type %T", response)
+	}
+	if err := json.Unmarshal(responseBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
 func (p *Protocol) sendCancelNotification(requestID transport.RequestId, reason string) error {
 	params := map[string]interface{}{
 		"requestId": requestID,
@@ -477,29 +673,39 @@ func (p *Protocol) sendCancelNotification(requestID transport.RequestId, reason
 	}
 	ctx := context.Background()
 
-	if err := p.transport.Send(ctx, transport.NewBaseMessageNotification(notification)); err != nil {
+	if err := p.send(ctx, transport.NewBaseMessageNotification(notification)); err != nil {
 		p.handleError(fmt.Errorf("failed to send cancel notification: %w", err))
 	}
 	return nil
 }
 
 func (p *Protocol) sendErrorResponse(requestID transport.RequestId, err error) error {
-	response := &transport.BaseJSONRPCError{
-		Jsonrpc: "2.0",
-		Id:      requestID,
-		Error: transport.BaseJSONRPCErrorInner{
-			Code:    -32000, // Internal error
-			Message: err.Error(),
-		},
-	}
-	ctx := context.Background()
-
-	if err := p.transport.Send(ctx, transport.NewBaseMessageError(response)); err != nil {
+	if err := p.send(context.Background(), newErrorResponse(requestID, err)); err != nil {
 		p.handleError(fmt.Errorf("failed to send error response: %w", err))
 	}
 	return nil
 }
 
+// newErrorResponse creates the error response to a request whose handler failed with err
+func newErrorResponse(requestID transport.RequestId, err error) *transport.BaseJsonRpcMessage {
+	// Handlers can pick the code by returning an RPCError, anything else is reported as a generic server error
+	errorInner := transport.BaseJSONRPCErrorInner{
+		Code:    ErrorCodeServerError,
+		Message: err.Error(),
+	}
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		errorInner.Code = rpcErr.Code
+		errorInner.Message = rpcErr.Message
+		errorInner.Data = rpcErr.Data
+	}
+	return transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+		Jsonrpc: "2.0",
+		Id:      requestID,
+		Error:   errorInner,
+	})
+}
+
 // Notification emits a notification, which is a one-way message that does not expect a response
 func (p *Protocol) Notification(method string, params interface{}) error {
 	if p.transport == nil {
@@ -518,7 +724,7 @@ func (p *Protocol) Notification(method string, params interface{}) error {
 	}
 	ctx := context.Background()
 
-	return p.transport.Send(ctx, transport.NewBaseMessageNotification(notification))
+	return p.send(ctx, transport.NewBaseMessageNotification(notification))
 }
 
 // SetRequestHandler registers a handler to invoke when this protocol object receives a request with the given method
@@ -542,6 +748,28 @@ func (p *Protocol) SetNotificationHandler(method string, handler func(notificati
 	p.mu.Unlock()
 }
 
+// RequestMethods returns the methods that have a request handler installed, in no particular order
+func (p *Protocol) RequestMethods() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	methods := make([]string, 0, len(p.requestHandlers))
+	for method := range p.requestHandlers {
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// NotificationMethods returns the methods that have a notification handler installed, in no particular order
+func (p *Protocol) NotificationMethods() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	methods := make([]string, 0, len(p.notificationHandlers))
+	for method := range p.notificationHandlers {
+		methods = append(methods, method)
+	}
+	return methods
+}
+
 // RemoveNotificationHandler removes the notification handler for the given method
 func (p *Protocol) RemoveNotificationHandler(method string) {
 	p.mu.Lock()
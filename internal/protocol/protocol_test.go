@@ -404,3 +404,305 @@ func TestProtocol_ErrorHandling(t *testing.T) {
 		t.Error("Error not received")
 	}
 }
+
+// TestProtocol_RequestInto verifies that the result of a request is unmarshalled into the given value
+// and that results which do not match the value are reported as errors.
+func TestProtocol_RequestInto(t *testing.T) {
+	p := NewProtocol(nil)
+	tr := testingutils.NewMockTransport()
+
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	respond := func(requestNumber int, result string) {
+		// Wait for the request to be sent, then answer it
+		for {
+			msgs := tr.GetMessages()
+			if len(msgs) >= requestNumber {
+				tr.SimulateMessage(transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+					Jsonrpc: "2.0",
+					Id:      msgs[requestNumber-1].JsonRpcRequest.Id,
+					Result:  json.RawMessage(result),
+				}))
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	type testResult struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	go respond(1, `{"name":"test","count":3}`)
+	var out testResult
+	if err := p.RequestInto(context.Background(), "test_method", nil, &out, nil); err != nil {
+		t.Fatalf("RequestInto failed: %v", err)
+	}
+	if out.Name != "test" || out.Count != 3 {
+		t.Errorf("Unexpected result: %+v", out)
+	}
+
+	go respond(2, `{"name":"test","count":"three"}`)
+	if err := p.RequestInto(context.Background(), "test_method", nil, &out, nil); err == nil {
+		t.Error("Expected error for a result that does not match the output type")
+	}
+}
+
+// TestProtocol_Interceptors verifies that interceptors can modify messages in both directions
+// and that an interceptor error aborts the send or dispatch.
+func TestProtocol_Interceptors(t *testing.T) {
+	p := NewProtocol(nil)
+	tr := testingutils.NewMockTransport()
+
+	p.WithOutboundInterceptor(func(message *transport.BaseJsonRpcMessage) error {
+		if message.Type == transport.BaseMessageTypeJSONRPCNotificationType && message.JsonRpcNotification.Method == "blocked" {
+			return errors.New("blocked by policy")
+		}
+		if message.Type == transport.BaseMessageTypeJSONRPCNotificationType {
+			message.JsonRpcNotification.Params = json.RawMessage(`{"signed":true}`)
+		}
+		return nil
+	})
+	p.WithInboundInterceptor(func(message *transport.BaseJsonRpcMessage) error {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return nil
+		}
+		if message.JsonRpcRequest.Method == "forbidden" {
+			return errors.New("not allowed")
+		}
+		if message.JsonRpcRequest.Method == "legacy_method" {
+			message.JsonRpcRequest.Method = "test_method"
+		}
+		return nil
+	})
+
+	handled := make(chan string, 1)
+	p.SetRequestHandler("test_method", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		handled <- req.Method
+		return map[string]interface{}{}, nil
+	})
+
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	// Outbound messages are modified before they reach the transport
+	if err := p.Notification("test_notification", map[string]interface{}{"signed": false}); err != nil {
+		t.Fatalf("Notification failed: %v", err)
+	}
+	msgs := tr.GetMessages()
+	if len(msgs) != 1 || string(msgs[0].JsonRpcNotification.Params) != `{"signed":true}` {
+		t.Fatalf("Expected the outbound interceptor to sign the notification, got %+v", msgs)
+	}
+
+	// An outbound error aborts the send
+	if err := p.Notification("blocked", nil); err == nil {
+		t.Error("Expected the outbound interceptor error to be returned")
+	}
+	if len(tr.GetMessages()) != 1 {
+		t.Error("Expected the blocked notification not to be sent")
+	}
+
+	// Inbound messages are modified before dispatch
+	tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Method:  "legacy_method",
+		Id:      1,
+	}))
+	select {
+	case method := <-handled:
+		if method != "test_method" {
+			t.Errorf("Expected handler to see the rewritten method, got %s", method)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Rewritten request was not dispatched")
+	}
+
+	// An inbound error answers the request with that error
+	tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Method:  "forbidden",
+		Id:      2,
+	}))
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		for _, msg := range tr.GetMessages() {
+			if msg.Type == transport.BaseMessageTypeJSONRPCErrorType && msg.JsonRpcError.Id == 2 {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("Expected an error response for the rejected request")
+}
+
+// batchingMockTransport is a mock of a request/response transport, that sends notifications in a batch with the response
+type batchingMockTransport struct {
+	*testingutils.MockTransport
+}
+
+func (t *batchingMockTransport) BatchesWithResponse() bool {
+	return true
+}
+
+func TestProtocol_NotifyAfterResponse(t *testing.T) {
+	if err := NotifyAfterResponse(context.Background(), "notifications/done", nil); err == nil {
+		t.Error("Expected an error outside of a request handler")
+	}
+
+	handlerCtx := make(chan context.Context, 1)
+	handler := func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		if err := NotifyAfterResponse(ctx, "notifications/done", map[string]string{"operation": "export"}); err != nil {
+			return nil, err
+		}
+		handlerCtx <- ctx
+		return map[string]interface{}{"status": "started"}, nil
+	}
+	request := transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      1,
+		Method:  "export",
+	})
+
+	t.Run("streaming transport", func(t *testing.T) {
+		p := NewProtocol(nil)
+		tr := testingutils.NewMockTransport()
+		if err := p.Connect(tr); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		p.SetRequestHandler("export", handler)
+		tr.SimulateMessage(request)
+		time.Sleep(50 * time.Millisecond)
+
+		msgs := tr.GetMessages()
+		if len(msgs) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(msgs))
+		}
+		if msgs[0].Type != transport.BaseMessageTypeJSONRPCResponseType {
+			t.Errorf("Expected the response first, got a %s", msgs[0].Type)
+		}
+		if msgs[1].Type != transport.BaseMessageTypeJSONRPCNotificationType || msgs[1].JsonRpcNotification.Method != "notifications/done" {
+			t.Fatalf("Expected the notification after the response, got %+v", msgs[1])
+		}
+		if string(msgs[1].JsonRpcNotification.Params) != `{"operation":"export"}` {
+			t.Errorf("Unexpected notification params %s", msgs[1].JsonRpcNotification.Params)
+		}
+
+		// Nothing can be queued once the response is sent
+		if err := NotifyAfterResponse(<-handlerCtx, "notifications/done", nil); err == nil {
+			t.Error("Expected an error after the response was sent")
+		}
+	})
+
+	t.Run("request/response transport", func(t *testing.T) {
+		p := NewProtocol(nil)
+		tr := &batchingMockTransport{testingutils.NewMockTransport()}
+		if err := p.Connect(tr); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		p.SetRequestHandler("export", handler)
+		tr.SimulateMessage(request)
+		time.Sleep(50 * time.Millisecond)
+		<-handlerCtx
+
+		msgs := tr.GetMessages()
+		if len(msgs) != 1 || msgs[0].Type != transport.BaseMessageTypeJSONRPCBatchType {
+			t.Fatalf("Expected a single batch, got %d messages", len(msgs))
+		}
+		batch := msgs[0].JsonRpcBatch
+		if len(batch) != 2 || batch[0].Type != transport.BaseMessageTypeJSONRPCResponseType || batch[1].Type != transport.BaseMessageTypeJSONRPCNotificationType {
+			t.Errorf("Expected the response followed by the notification, got %+v", batch)
+		}
+	})
+}
+
+// TestProtocol_JSONRPCVersion verifies that every message is sent with jsonrpc set to "2.0",
+// even when it was built or rewritten without it
+func TestProtocol_JSONRPCVersion(t *testing.T) {
+	p := NewProtocol(nil)
+	tr := &batchingMockTransport{testingutils.NewMockTransport()}
+	// Strip the version that the protocol's own call sites set
+	var strip func(message *transport.BaseJsonRpcMessage)
+	strip = func(message *transport.BaseJsonRpcMessage) {
+		switch message.Type {
+		case transport.BaseMessageTypeJSONRPCRequestType:
+			message.JsonRpcRequest.Jsonrpc = ""
+		case transport.BaseMessageTypeJSONRPCNotificationType:
+			message.JsonRpcNotification.Jsonrpc = ""
+		case transport.BaseMessageTypeJSONRPCResponseType:
+			message.JsonRpcResponse.Jsonrpc = ""
+		case transport.BaseMessageTypeJSONRPCErrorType:
+			message.JsonRpcError.Jsonrpc = ""
+		}
+		for _, m := range message.JsonRpcBatch {
+			strip(m)
+		}
+	}
+	p.WithOutboundInterceptor(func(message *transport.BaseJsonRpcMessage) error {
+		strip(message)
+		return nil
+	})
+	if err := p.Connect(tr); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	p.SetRequestHandler("ok", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		if err := NotifyAfterResponse(ctx, "notifications/done", nil); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{}, nil
+	})
+	p.SetRequestHandler("fail", func(ctx context.Context, req *transport.BaseJSONRPCRequest, extra RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return nil, errors.New("failed")
+	})
+
+	tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{Jsonrpc: "2.0", Id: 1, Method: "ok"}))
+	tr.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{Jsonrpc: "2.0", Id: 2, Method: "fail"}))
+	if err := p.Notification("notifications/test", nil); err != nil {
+		t.Fatalf("Notification failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p.Request(ctx, "test", nil, nil)
+
+	msgs := tr.GetMessages()
+	seen := map[transport.BaseMessageType]bool{}
+	var check func(message *transport.BaseJsonRpcMessage)
+	check = func(message *transport.BaseJsonRpcMessage) {
+		seen[message.Type] = true
+		if message.Type != transport.BaseMessageTypeJSONRPCBatchType {
+			raw, err := json.Marshal(message)
+			if err != nil {
+				t.Fatalf("Failed to marshal message: %v", err)
+			}
+			var fields struct {
+				Jsonrpc string `json:"jsonrpc"`
+			}
+			if err := json.Unmarshal(raw, &fields); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+			if fields.Jsonrpc != "2.0" {
+				t.Errorf("Expected jsonrpc 2.0 in %s", raw)
+			}
+		}
+		for _, m := range message.JsonRpcBatch {
+			check(m)
+		}
+	}
+	for _, message := range msgs {
+		check(message)
+	}
+	for _, messageType := range []transport.BaseMessageType{
+		transport.BaseMessageTypeJSONRPCRequestType,
+		transport.BaseMessageTypeJSONRPCNotificationType,
+		transport.BaseMessageTypeJSONRPCResponseType,
+		transport.BaseMessageTypeJSONRPCErrorType,
+		transport.BaseMessageTypeJSONRPCBatchType,
+	} {
+		if !seen[messageType] {
+			t.Errorf("Expected a %s to be sent", messageType)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package mcp_golang
+
+import (
+	"sync"
+	"time"
+)
+
+// WithListChangedDebounce coalesces list_changed notifications of the same kind that happen within delay of each other.
+// Each change restarts the delay, and a single notification is sent once no change happened for delay,
+// e.g. when registering many tools at once.
+func WithListChangedDebounce(delay time.Duration) ServerOptions {
+	return func(s *Server) {
+		s.listChangedDebouncer = newListChangedDebouncer(delay, func(method string) {
+			if err := s.notification(method, nil); err != nil {
+				s.logf("error: %s", err.Error())
+			}
+		})
+	}
+}
+
+// listChangedDebouncer delays list_changed notifications until their method had no changes for delay
+type listChangedDebouncer struct {
+	mu     sync.Mutex
+	delay  time.Duration
+	timers map[string]*time.Timer
+	send   func(method string)
+}
+
+func newListChangedDebouncer(delay time.Duration, send func(method string)) *listChangedDebouncer {
+	return &listChangedDebouncer{
+		delay:  delay,
+		timers: make(map[string]*time.Timer),
+		send:   send,
+	}
+}
+
+// notify schedules a notification for method, replacing one that is still pending
+func (d *listChangedDebouncer) notify(method string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if timer, ok := d.timers[method]; ok && timer.Stop() {
+		timer.Reset(d.delay)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d.delay, func() {
+		d.mu.Lock()
+		// A change that came in while this timer fired has already scheduled a new one
+		if d.timers[method] == timer {
+			delete(d.timers, method)
+		}
+		d.mu.Unlock()
+		d.send(method)
+	})
+	d.timers[method] = timer
+}
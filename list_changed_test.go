@@ -0,0 +1,42 @@
+package mcp_golang
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListChangedDebounce(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport, WithListChangedDebounce(50*time.Millisecond))
+	require.NoError(t, server.Serve())
+
+	for i := 0; i < 5; i++ {
+		err := server.RegisterTool(fmt.Sprintf("tool-%d", i), "A tool", func(arguments args) (*ToolResponse, error) {
+			return NewToolResponse(), nil
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, server.DeregisterTool("tool-0"))
+	require.NoError(t, server.RegisterPrompt("prompt", "A prompt", func(arguments args) (*PromptResponse, error) {
+		return NewPromptResponse("prompt"), nil
+	}))
+	assert.Empty(t, mockTransport.GetMessages(), "notifications must wait for the changes to settle")
+
+	// One notification per kind of list once no change happened for the delay
+	assert.Eventually(t, func() bool {
+		return len(mockTransport.GetMessages()) == 2
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	messages := mockTransport.GetMessages()
+	require.Len(t, messages, 2)
+	methods := []string{messages[0].JsonRpcNotification.Method, messages[1].JsonRpcNotification.Method}
+	assert.ElementsMatch(t, []string{"notifications/tools/list_changed", "notifications/prompts/list_changed"}, methods)
+}
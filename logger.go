@@ -0,0 +1,139 @@
+package mcp_golang
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Logger receives the server's diagnostic messages, such as errors returned by request handlers.
+// *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// WithLogger sends the server's diagnostic messages to logger instead of stderr
+func WithLogger(logger Logger) ServerOptions {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithLogFile sends the server's diagnostic messages to the file at path.
+// Once the file grows past maxSizeMB megabytes it is renamed to path + ".1", replacing any previous backup, and a new file is started.
+// A maxSizeMB of zero or less never rotates the file. The file is closed once the last of the server's transports is closed.
+// This keeps diagnostics out of stdout and stderr, e.g. for servers that are run as a subprocess over stdio.
+// If the file cannot be opened, the error is printed to stderr and the server logs to stderr.
+func WithLogFile(path string, maxSizeMB int) ServerOptions {
+	return func(s *Server) {
+		file, err := newRotatingFile(path, int64(maxSizeMB)*1024*1024)
+		if err != nil {
+			println("failed to open log file:", err.Error())
+			return
+		}
+		if s.logFile != nil {
+			s.logFile.Close()
+		}
+		s.logFile = file
+		s.logger = log.New(file, "", log.LstdFlags)
+	}
+}
+
+// logf writes a diagnostic message to the server's logger, or to stderr if it has none
+func (s *Server) logf(format string, v ...any) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+		return
+	}
+	println(fmt.Sprintf(format, v...))
+}
+
+// rotatingFile is an io.Writer that starts a new file once the current one reaches maxBytes, or never if maxBytes is zero or less
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+	closed   bool
+}
+
+func newRotatingFile(path string, maxBytes int64) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:     path,
+		maxBytes: maxBytes,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the file at r.path for appending
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", r.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file %s: %w", r.path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate moves the current file to the backup path and opens a new file.
+// If the file can't be moved, the current file is opened again so that logging continues in it.
+func (r *rotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file %s: %w", r.path, err)
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		if openErr := r.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate log file %s: %w", r.path, err)
+	}
+	return r.open()
+}
+
+// Write implements io.Writer. A single write is never split across files.
+// If rotating fails the write goes to the current file, and rotating is tried again on the next write.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.file != nil && r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		r.rotate()
+	}
+	if r.file == nil {
+		// A previous rotation lost the file, try to get it back
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file, later writes fail with os.ErrClosed
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,89 @@
+package mcp_golang
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFileRotatesPastSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	file, err := newRotatingFile(path, 16)
+	require.NoError(t, err)
+
+	_, err = file.Write([]byte("first line\n"))
+	require.NoError(t, err)
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err), "file must not rotate below the size limit")
+
+	// This write takes the file past 16 bytes, so it goes to a new file
+	_, err = file.Write([]byte("second line\n"))
+	require.NoError(t, err)
+
+	backup, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "first line\n", string(backup))
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second line\n", string(current))
+}
+
+func TestWithLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	server := NewServer(testingutils.NewMockTransport(), WithLogFile(path, 1))
+	require.NotNil(t, server.logger)
+
+	server.logger.Printf("error: %s", "tool failed")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(contents), "error: tool failed\n"), "unexpected log contents %q", contents)
+}
+
+func TestRotatingFileKeepsLoggingWhenRotationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	// A non-empty directory at the backup path makes the rename fail
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o755))
+	file, err := newRotatingFile(path, 16)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first line\n", "second line\n", "third line\n"} {
+		_, err = file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first line\nsecond line\nthird line\n", string(current))
+}
+
+func TestRotatingFileWithoutSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	file, err := newRotatingFile(path, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = file.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err), "file must not rotate without a size limit")
+}
+
+func TestWithLogFileClosedWithServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport, WithLogFile(path, 1))
+	require.NoError(t, server.Serve())
+
+	require.NoError(t, mockTransport.Close())
+
+	_, err := server.logFile.Write([]byte("after close\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
@@ -0,0 +1,74 @@
+package mcp_golang
+
+import (
+	"encoding/json"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// Level is the severity of a log message sent with notifications/message.
+// These map to syslog message severities, as specified in RFC-5424.
+type Level string
+
+const (
+	LevelDebug     Level = "debug"
+	LevelInfo      Level = "info"
+	LevelNotice    Level = "notice"
+	LevelWarning   Level = "warning"
+	LevelError     Level = "error"
+	LevelCritical  Level = "critical"
+	LevelAlert     Level = "alert"
+	LevelEmergency Level = "emergency"
+)
+
+// Valid reports whether l is one of the known levels
+func (l Level) Valid() bool {
+	switch l {
+	case LevelDebug, LevelInfo, LevelNotice, LevelWarning, LevelError, LevelCritical, LevelAlert, LevelEmergency:
+		return true
+	}
+	return false
+}
+
+// LoggingMessageNotification is the payload of a notifications/message notification.
+type LoggingMessageNotification struct {
+	// The severity of this log message.
+	Level Level `json:"level" yaml:"level" mapstructure:"level"`
+
+	// An optional name of the logger issuing this message.
+	Logger *string `json:"logger,omitempty" yaml:"logger,omitempty" mapstructure:"logger,omitempty"`
+
+	// The data to be logged, such as a string message or an object. Any JSON
+	// serializable type is allowed here.
+	Data json.RawMessage `json:"data" yaml:"data" mapstructure:"data"`
+
+	// Metadata of the message, set for messages sent with a logger from LoggerFromContext.
+	Meta *LoggingMessageMeta `json:"_meta,omitempty" yaml:"_meta,omitempty" mapstructure:"_meta,omitempty"`
+}
+
+// LoggingMessageMeta is the _meta of a notifications/message notification
+type LoggingMessageMeta struct {
+	// The id of the request the message was logged while handling.
+	RequestId *transport.RequestId `json:"requestId,omitempty" yaml:"requestId,omitempty" mapstructure:"requestId,omitempty"`
+}
+
+// StructuredData decodes the data of the log message as a JSON object.
+// It returns an error if the message was not sent with structured data, e.g. when the data is a plain string.
+func (n *LoggingMessageNotification) StructuredData() (map[string]any, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(n.Data, &fields); err != nil {
+		return nil, errors.Wrap(err, "log message data is not a JSON object")
+	}
+	return fields, nil
+}
+
+// Text decodes the data of the log message as a string.
+// It returns an error if the message was sent with structured data.
+func (n *LoggingMessageNotification) Text() (string, error) {
+	var text string
+	if err := json.Unmarshal(n.Data, &text); err != nil {
+		return "", errors.Wrap(err, "log message data is not a string")
+	}
+	return text, nil
+}
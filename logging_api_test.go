@@ -0,0 +1,92 @@
+package mcp_golang
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredLogNotificationRoundTrip(t *testing.T) {
+	serverTransport := testingutils.NewMockTransport()
+	server := NewServer(serverTransport)
+	require.NoError(t, server.Serve())
+
+	fields := map[string]any{
+		"request": map[string]any{
+			"path":   "/weather",
+			"params": []any{"london", "celsius"},
+		},
+		"durationMs": 42.5,
+	}
+	require.NoError(t, server.SendStructuredLogNotification(LevelWarning, "http", fields))
+
+	messages := serverTransport.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "notifications/message", messages[0].JsonRpcNotification.Method)
+
+	// Send the serialized notification through to a client
+	serialized, err := json.Marshal(messages[0])
+	require.NoError(t, err)
+	var notification transport.BaseJSONRPCNotification
+	require.NoError(t, json.Unmarshal(serialized, &notification))
+
+	clientTransport := testingutils.NewMockTransport()
+	client := NewClient(clientTransport)
+	received := make(chan *LoggingMessageNotification, 1)
+	client.OnLogMessage(func(n *LoggingMessageNotification) {
+		received <- n
+	})
+	require.NoError(t, client.protocol.Connect(clientTransport))
+	clientTransport.SimulateMessage(transport.NewBaseMessageNotification(&notification))
+
+	select {
+	case logMessage := <-received:
+		assert.Equal(t, LevelWarning, logMessage.Level)
+		require.NotNil(t, logMessage.Logger)
+		assert.Equal(t, "http", *logMessage.Logger)
+		decoded, err := logMessage.StructuredData()
+		require.NoError(t, err)
+		assert.Equal(t, fields, decoded)
+		_, err = logMessage.Text()
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("log message was not delivered to the client")
+	}
+}
+
+func TestLogMessageNotificationText(t *testing.T) {
+	serverTransport := testingutils.NewMockTransport()
+	server := NewServer(serverTransport)
+	require.NoError(t, server.Serve())
+
+	require.NoError(t, server.SendLogMessageNotification(LevelInfo, "", "started"))
+
+	messages := serverTransport.GetMessages()
+	require.Len(t, messages, 1)
+	var logMessage LoggingMessageNotification
+	require.NoError(t, json.Unmarshal(messages[0].JsonRpcNotification.Params, &logMessage))
+	assert.Nil(t, logMessage.Logger)
+	text, err := logMessage.Text()
+	require.NoError(t, err)
+	assert.Equal(t, "started", text)
+}
+
+func TestLogMessageNotificationInvalidLevel(t *testing.T) {
+	serverTransport := testingutils.NewMockTransport()
+	server := NewServer(serverTransport)
+	require.NoError(t, server.Serve())
+
+	for _, level := range []Level{"", "verbose", "INFO"} {
+		assert.ErrorContains(t, server.SendLogMessageNotification(level, "http", "request served"), "invalid log level")
+		assert.ErrorContains(t, server.SendStructuredLogNotification(level, "http", nil), "invalid log level")
+	}
+	assert.Empty(t, serverTransport.GetMessages())
+
+	require.NoError(t, server.SendLogMessageNotification(LevelInfo, "http", "request served"))
+	assert.Len(t, serverTransport.GetMessages(), 1)
+}
@@ -16,6 +16,8 @@ type ListPromptsResponse struct {
 	Prompts []*PromptSchema `json:"prompts" yaml:"prompts" mapstructure:"prompts"`
 	// NextCursor is a cursor for pagination. If not nil, there are more prompts available.
 	NextCursor *string `json:"nextCursor,omitempty" yaml:"nextCursor,omitempty" mapstructure:"nextCursor,omitempty"`
+	// Total is the number of prompts across all pages. It is only set when the server paginates.
+	Total *int `json:"total,omitempty" yaml:"total,omitempty" mapstructure:"total,omitempty"`
 }
 
 // A PromptSchema or prompt template that the server offers.
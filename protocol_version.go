@@ -0,0 +1,28 @@
+package mcp_golang
+
+import "time"
+
+// ProtocolVersionAtLeast reports whether the protocol version version is the same as or newer than minimum.
+// MCP versions are dates, e.g. 2024-11-05, and are ordered by date. Versions that aren't dates never compare as newer.
+func ProtocolVersionAtLeast(version string, minimum string) bool {
+	versionDate, err := time.Parse(time.DateOnly, version)
+	if err != nil {
+		return false
+	}
+	minimumDate, err := time.Parse(time.DateOnly, minimum)
+	if err != nil {
+		return false
+	}
+	return !versionDate.Before(minimumDate)
+}
+
+// ProtocolVersion returns the protocol version the server agreed on during initialization, or "" before Initialize
+func (c *Client) ProtocolVersion() string {
+	return c.protocolVersion
+}
+
+// ProtocolVersionAtLeast reports whether the protocol version agreed on with the server is the same as or newer than
+// minimum, e.g. to only use a feature with servers that support it
+func (c *Client) ProtocolVersionAtLeast(minimum string) bool {
+	return ProtocolVersionAtLeast(c.protocolVersion, minimum)
+}
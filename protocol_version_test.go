@@ -0,0 +1,34 @@
+package mcp_golang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtocolVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		minimum string
+		want    bool
+	}{
+		{"2024-11-05", "2024-11-05", true},
+		{"2025-03-26", "2024-11-05", true},
+		{"2025-06-18", "2025-03-26", true},
+		{"2024-11-05", "2025-03-26", false},
+		{"2024-10-07", "2024-11-05", false},
+		{"1.0", "2024-11-05", false},
+		{"", "2024-11-05", false},
+		{"2024-11-05", "latest", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ProtocolVersionAtLeast(tt.version, tt.minimum), "%s at least %s", tt.version, tt.minimum)
+	}
+}
+
+func TestClientProtocolVersion(t *testing.T) {
+	client := newInProcessClient(t, NewServer(nil))
+	assert.Equal(t, "2024-11-05", client.ProtocolVersion())
+	assert.True(t, client.ProtocolVersionAtLeast("2024-11-05"))
+	assert.False(t, client.ProtocolVersionAtLeast("2025-03-26"))
+}
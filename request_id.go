@@ -0,0 +1,33 @@
+package mcp_golang
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// RequestIDFromContext returns the JSON-RPC id of the request a handler is serving, as the client sent it.
+// Transports that rewrite ids internally, such as the HTTP transports, still report the client's original id.
+func RequestIDFromContext(ctx context.Context) (transport.RequestId, bool) {
+	return transport.RequestIDFromContext(ctx)
+}
+
+// ClaimsFromContext returns the claims of the access token the request a handler is serving was authorized with.
+// It is only set by transports that validate tokens, e.g. an HTTP transport created with WithJWTValidator.
+func ClaimsFromContext(ctx context.Context) (transport.Claims, bool) {
+	return transport.ClaimsFromContext(ctx)
+}
+
+// HTTPHeaderFromContext returns the headers of the HTTP request the request a handler is serving was received in,
+// e.g. to read its Accept-Language. It is only set by the HTTP transport.
+func HTTPHeaderFromContext(ctx context.Context) (http.Header, bool) {
+	return transport.HTTPHeaderFromContext(ctx)
+}
+
+// ConnectionContextFromContext returns a context that is cancelled once the client of the request a handler is serving
+// disconnects. Use it instead of the request's context for work that should continue after the response is sent,
+// e.g. a watch started by a tool. It is only set by transports that keep a connection open, such as stdio.
+func ConnectionContextFromContext(ctx context.Context) (context.Context, bool) {
+	return transport.ConnectionFromContext(ctx)
+}
@@ -0,0 +1,70 @@
+package mcp_golang
+
+import (
+	"context"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/pkg/errors"
+)
+
+// RequestLogger sends log messages on behalf of the request a handler is serving, see LoggerFromContext
+type RequestLogger struct {
+	protocol  *protocol.Protocol
+	requestId *transport.RequestId
+}
+
+type requestLoggerKey struct{}
+
+// LoggerFromContext returns a logger whose notifications/message log messages carry the id of the request a handler
+// is serving in their _meta, so that clients can tell apart the logs of concurrent tool calls.
+// The messages are only sent to the client that made the request. ctx must be the context passed to the handler,
+// otherwise the logger's methods return an error.
+func LoggerFromContext(ctx context.Context) *RequestLogger {
+	logger, ok := ctx.Value(requestLoggerKey{}).(*RequestLogger)
+	if !ok {
+		return &RequestLogger{}
+	}
+	return logger
+}
+
+// Log sends a log message with a string as its data
+func (l *RequestLogger) Log(level Level, logger string, message string) error {
+	return l.send(level, logger, message)
+}
+
+// LogStructured sends a log message with fields as its data, see Server.SendStructuredLogNotification
+func (l *RequestLogger) LogStructured(level Level, logger string, fields map[string]any) error {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	return l.send(level, logger, fields)
+}
+
+func (l *RequestLogger) send(level Level, logger string, data any) error {
+	if l.protocol == nil {
+		return errors.New("context is not the context of a request handler")
+	}
+	if !level.Valid() {
+		return errors.Errorf("invalid log level %q", level)
+	}
+	notification, err := newLogMessageNotification(level, logger, data)
+	if err != nil {
+		return err
+	}
+	if l.requestId != nil {
+		notification.Meta = &LoggingMessageMeta{RequestId: l.requestId}
+	}
+	return l.protocol.Notification("notifications/message", notification)
+}
+
+// withRequestLogger wraps handler so that its context carries a RequestLogger for the request
+func (s *Server) withRequestLogger(pr *protocol.Protocol, handler requestHandler) requestHandler {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		logger := &RequestLogger{protocol: pr}
+		if id, ok := transport.RequestIDFromContext(ctx); ok {
+			logger.requestId = &id
+		}
+		return handler(context.WithValue(ctx, requestLoggerKey{}, logger), request, extra)
+	}
+}
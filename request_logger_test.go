@@ -0,0 +1,54 @@
+package mcp_golang
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	type args struct {
+		Step string `json:"step"`
+	}
+	requestIds := make(chan int64, 1)
+	server := NewServer(nil)
+	err := server.RegisterTool("work", "Logs its progress", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		id, _ := RequestIDFromContext(ctx)
+		requestIds <- int64(id)
+		if err := LoggerFromContext(ctx).Log(LevelInfo, "worker", "running "+arguments.Step); err != nil {
+			return nil, err
+		}
+		return NewToolResponse(NewTextContent("done")), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	messages := make(chan *LoggingMessageNotification, 1)
+	client.OnLogMessage(func(notification *LoggingMessageNotification) {
+		messages <- notification
+	})
+
+	_, err = client.CallTool(context.Background(), "work", args{Step: "build"})
+	require.NoError(t, err)
+
+	select {
+	case message := <-messages:
+		text, err := message.Text()
+		require.NoError(t, err)
+		assert.Equal(t, "running build", text)
+		assert.Equal(t, "worker", *message.Logger)
+		require.NotNil(t, message.Meta)
+		require.NotNil(t, message.Meta.RequestId)
+		assert.Equal(t, <-requestIds, int64(*message.Meta.RequestId))
+	case <-time.After(time.Second):
+		t.Fatal("log message was not received")
+	}
+}
+
+func TestLoggerFromContextOutsideHandler(t *testing.T) {
+	err := LoggerFromContext(context.Background()).Log(LevelInfo, "", "lost")
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package mcp_golang
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// WithMethodTimeout limits how long the server handles requests for method, e.g. "tools/call" or "resources/read".
+// The context passed to the handler is cancelled once the timeout passes, and the client receives a request timeout error
+// even if the handler keeps running. It takes precedence over WithDefaultTimeout.
+func WithMethodTimeout(method string, timeout time.Duration) ServerOptions {
+	return func(s *Server) {
+		if s.methodTimeouts == nil {
+			s.methodTimeouts = make(map[string]time.Duration)
+		}
+		s.methodTimeouts[method] = timeout
+	}
+}
+
+// WithDefaultTimeout limits how long the server handles requests for methods that have no timeout set with WithMethodTimeout.
+func WithDefaultTimeout(timeout time.Duration) ServerOptions {
+	return func(s *Server) {
+		s.defaultTimeout = timeout
+	}
+}
+
+type requestHandler func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error)
+
+// withTimeout wraps handler so that it is cancelled after the timeout of method, if there is one
+func (s *Server) withTimeout(method string, handler requestHandler) requestHandler {
+	timeout, ok := s.methodTimeouts[method]
+	if !ok {
+		timeout = s.defaultTimeout
+	}
+	if timeout <= 0 {
+		return handler
+	}
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		extra.Context = ctx
+
+		type result struct {
+			body transport.JsonRpcBody
+			err  error
+		}
+		// Buffered so that a handler that ignores its context can still finish after we stop waiting for it
+		done := make(chan result, 1)
+		go func() {
+			body, err := handler(ctx, request, extra)
+			done <- result{body: body, err: err}
+		}()
+
+		select {
+		case r := <-done:
+			return r.body, r.err
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, protocol.NewRPCError(protocol.ErrorCodeRequestTimeout, fmt.Sprintf("%s timed out after %s", method, timeout), nil)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package mcp_golang
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMethodTimeout(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	handlerErr := make(chan error, 1)
+	server := NewServer(nil, WithMethodTimeout("tools/call", 50*time.Millisecond), WithDefaultTimeout(time.Minute))
+	err := server.RegisterTool("slow", "Takes longer than its timeout", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		select {
+		case <-ctx.Done():
+			handlerErr <- ctx.Err()
+		case <-time.After(5 * time.Second):
+			handlerErr <- nil
+		}
+		return NewToolResponse(NewTextContent("done")), nil
+	})
+	require.NoError(t, err)
+	client := newInProcessClient(t, server)
+
+	start := time.Now()
+	_, err = client.CallTool(context.Background(), "slow", args{})
+	assert.ErrorIs(t, err, ErrRequestTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// The handler's context is the one that timed out
+	select {
+	case err := <-handlerErr:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("Handler context was not cancelled")
+	}
+
+	// Methods without their own timeout use the default
+	_, err = client.ListTools(context.Background(), nil)
+	assert.NoError(t, err)
+}
@@ -1,13 +1,83 @@
 package mcp_golang
 
-// There are no arguments to the resource api
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// There are no arguments to the resource api, but context-aware handlers can read the query of the requested URI
+// with ResourceQueryFromContext and the requested byte range with ResourceRangeFromContext.
+
+type resourceQueryKey struct{}
+
+type resourceRangeKey struct{}
+
+// ResourceQueryFromContext returns the query parameters of the URI a resource was read with,
+// e.g. limit=10 for db://table?limit=10 when the resource is registered as db://table.
+// It returns nil if the URI had no query.
+func ResourceQueryFromContext(ctx context.Context) url.Values {
+	query, _ := ctx.Value(resourceQueryKey{}).(url.Values)
+	return query
+}
+
+// ResourceRangeFromContext returns the byte range the client asked for, or nil if it asked for the full contents.
+// Handlers that honor it should return only that range of the contents, and mark the response WithRange.
+func ResourceRangeFromContext(ctx context.Context) *ResourceRange {
+	byteRange, _ := ctx.Value(resourceRangeKey{}).(*ResourceRange)
+	return byteRange
+}
 
 type ResourceResponse struct {
 	Contents []*EmbeddedResource `json:"contents"`
+
+	// The size of the resource in bytes, if known.
+	Size *int64 `json:"size,omitempty"`
+
+	// An opaque version identifier of the resource. Clients can send it back when reading the resource again
+	// to avoid transferring unchanged contents.
+	ETag *string `json:"etag,omitempty"`
+
+	// When the resource was last modified, if known.
+	LastModified *time.Time `json:"lastModified,omitempty"`
+
+	// Set by the server instead of sending contents when the client's etag matches the current etag of the resource.
+	NotModified bool `json:"notModified,omitempty"`
+
+	// The byte range of the resource that the contents hold. Not set when the contents are the full resource.
+	Range *ResourceRange `json:"range,omitempty"`
 }
 
+// NewResourceResponse creates a response to a resources/read request. A handler can return several contents for one uri,
+// e.g. the files of a directory, each with its own uri and MIME type. Clients receive them all, in order.
 func NewResourceResponse(contents ...*EmbeddedResource) *ResourceResponse {
 	return &ResourceResponse{
 		Contents: contents,
 	}
 }
+
+// WithSize sets the size of the resource in bytes
+func (r *ResourceResponse) WithSize(size int64) *ResourceResponse {
+	r.Size = &size
+	return r
+}
+
+// WithETag sets the etag of the resource. When a client reads the resource with a matching etag,
+// the server responds with NotModified set and no contents. The HTTP transport also sends it as the ETag header.
+func (r *ResourceResponse) WithETag(etag string) *ResourceResponse {
+	r.ETag = &etag
+	return r
+}
+
+// WithLastModified sets when the resource was last modified
+func (r *ResourceResponse) WithLastModified(lastModified time.Time) *ResourceResponse {
+	r.LastModified = &lastModified
+	return r
+}
+
+// WithRange marks the contents as holding only the bytes from start up to but not including end.
+// Use WithSize to report the total size of the resource.
+func (r *ResourceResponse) WithRange(start int64, end int64) *ResourceResponse {
+	r.Range = &ResourceRange{Start: start, End: end}
+	return r
+}
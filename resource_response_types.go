@@ -4,6 +4,29 @@ type readResourceRequestParams struct {
 	// The URI of the resource to read. The URI can use any protocol; it is up to the
 	// server how to interpret it.
 	Uri string `json:"uri" yaml:"uri" mapstructure:"uri"`
+
+	// The etag of the contents the client already has. If it matches the current etag of the resource,
+	// the server responds with notModified instead of the contents.
+	IfNoneMatch *string `json:"ifNoneMatch,omitempty" yaml:"ifNoneMatch,omitempty" mapstructure:"ifNoneMatch,omitempty"`
+
+	// The byte range of the contents to read. Handlers that don't support ranges return the full contents.
+	Range *ResourceRange `json:"range,omitempty" yaml:"range,omitempty" mapstructure:"range,omitempty"`
+}
+
+// ResourceRange is a range of bytes of a resource's contents, from Start up to but not including End.
+type ResourceRange struct {
+	Start int64 `json:"start" yaml:"start" mapstructure:"start"`
+	End   int64 `json:"end" yaml:"end" mapstructure:"end"`
+}
+
+// ResourceListFilter narrows a resources/list request to a subset of the server's resources.
+// Both fields are optional; when both are set a resource must match both.
+type ResourceListFilter struct {
+	// Only return resources whose URI uses this scheme, e.g. "file" for file:// resources.
+	Scheme *string `json:"scheme,omitempty" yaml:"scheme,omitempty" mapstructure:"scheme,omitempty"`
+
+	// Only return resources whose URI starts with this prefix.
+	Prefix *string `json:"prefix,omitempty" yaml:"prefix,omitempty" mapstructure:"prefix,omitempty"`
 }
 
 // The server's response to a resources/list request from the client.
@@ -12,6 +35,8 @@ type ListResourcesResponse struct {
 	Resources []*ResourceSchema `json:"resources" yaml:"resources" mapstructure:"resources"`
 	// NextCursor is a cursor for pagination. If not nil, there are more resources available.
 	NextCursor *string `json:"nextCursor,omitempty" yaml:"nextCursor,omitempty" mapstructure:"nextCursor,omitempty"`
+	// Total is the number of resources across all pages, after filtering. It is only set when the server paginates.
+	Total *int `json:"total,omitempty" yaml:"total,omitempty" mapstructure:"total,omitempty"`
 }
 
 // A known resource that the server is capable of reading.
@@ -0,0 +1,77 @@
+package mcp_golang
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// WithResourceSingleflight makes concurrent reads of the same resource share a single execution of its handler,
+// which cuts the load of expensive resources that many clients read at once. Reads are identical if they have the same
+// uri and range. The shared handler runs with the context of the first read, without its cancellation.
+func WithResourceSingleflight(enabled bool) ServerOptions {
+	return func(s *Server) {
+		if enabled {
+			s.resourceReads = newResourceReadGroup()
+		} else {
+			s.resourceReads = nil
+		}
+	}
+}
+
+// resourceReadGroup coalesces concurrent identical resource reads, keyed by uri and range
+type resourceReadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*resourceReadCall
+}
+
+type resourceReadCall struct {
+	done     chan struct{}
+	response *resourceResponseSent
+	// The number of other reads waiting for the response
+	waiters int
+}
+
+func newResourceReadGroup() *resourceReadGroup {
+	return &resourceReadGroup{calls: make(map[string]*resourceReadCall)}
+}
+
+// resourceReadKey identifies identical reads
+func resourceReadKey(uri string, resourceRange *ResourceRange) string {
+	if resourceRange == nil {
+		return uri
+	}
+	return fmt.Sprintf("%s\x00%d-%d", uri, resourceRange.Start, resourceRange.End)
+}
+
+// read calls handler, unless a read with the same key is in flight, in which case it waits for that read's response.
+// Waiting stops when ctx is done, the shared handler keeps running for the other reads.
+func (g *resourceReadGroup) read(ctx context.Context, key string, handler func(context.Context) *resourceResponseSent) *resourceResponseSent {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return newResourceResponseSentError(ctx.Err())
+		}
+		// The shared handler panicked, this read runs it on its own
+		if call.response == nil {
+			return handler(ctx)
+		}
+		return call.response
+	}
+	call := &resourceReadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.response = handler(context.WithoutCancel(ctx))
+	return call.response
+}
@@ -0,0 +1,87 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceSingleflight(t *testing.T) {
+	const reads = 10
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := NewServer(testingutils.NewMockTransport(), WithResourceSingleflight(true))
+	require.NoError(t, server.RegisterResource("file:///report.txt", "report", "An expensive report", "text/plain", func() (*ResourceResponse, error) {
+		calls.Add(1)
+		<-release
+		return NewResourceResponse(NewTextEmbeddedResource("file:///report.txt", "report", "text/plain")), nil
+	}))
+
+	params, err := json.Marshal(readResourceRequestParams{Uri: "file:///report.txt"})
+	require.NoError(t, err)
+	responses := make([]transport.JsonRpcBody, reads)
+	var wg sync.WaitGroup
+	for i := 0; i < reads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := server.handleResourceCalls(context.Background(), &transport.BaseJSONRPCRequest{Params: params}, protocol.RequestHandlerExtra{})
+			assert.NoError(t, err)
+			responses[i] = response
+		}(i)
+	}
+
+	// Release the handler once every other read waits for its response
+	key := resourceReadKey("file:///report.txt", nil)
+	require.Eventually(t, func() bool {
+		server.resourceReads.mu.Lock()
+		defer server.resourceReads.mu.Unlock()
+		call, ok := server.resourceReads.calls[key]
+		return ok && call.waiters == reads-1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, response := range responses {
+		sent, ok := response.(*resourceResponseSent)
+		require.True(t, ok)
+		require.NoError(t, sent.Error)
+		assert.Equal(t, "report", sent.Response.Contents[0].TextResourceContents.Text)
+	}
+
+	// Reads that don't overlap run the handler again
+	_, err = server.handleResourceCalls(context.Background(), &transport.BaseJSONRPCRequest{Params: params}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestResourceSingleflightWaiterCancelled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	group := newResourceReadGroup()
+	started := make(chan struct{})
+	go group.read(context.Background(), "file:///report.txt", func(ctx context.Context) *resourceResponseSent {
+		close(started)
+		<-release
+		return newResourceResponseSent(NewResourceResponse())
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response := group.read(ctx, "file:///report.txt", func(ctx context.Context) *resourceResponseSent {
+		t.Error("the handler must not run again while a read is in flight")
+		return nil
+	})
+	assert.ErrorIs(t, response.Error, context.Canceled)
+}
@@ -0,0 +1,42 @@
+package mcp_golang
+
+import (
+	"time"
+)
+
+// WatchResource polls check every interval and sends a notifications/resources/updated notification for uri
+// whenever it reports that the resource changed, e.g. for resources backed by a system that can't push changes.
+// This server has no per-resource subscriptions, so the notification goes to every connected client.
+// Errors returned by check are logged and polling continues. The poller stops once the server's transports are closed.
+func (s *Server) WatchResource(uri string, interval time.Duration, check func() (bool, error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+			changed, err := check()
+			if err != nil {
+				s.logf("error: failed to check resource %s for changes: %s", uri, err.Error())
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := s.sendResourceUpdatedNotification(uri); err != nil {
+				s.logf("error: %s", err.Error())
+			}
+		}
+	}()
+}
+
+// sendResourceUpdatedNotification sends notifications/resources/updated for uri to the clients of every transport
+func (s *Server) sendResourceUpdatedNotification(uri string) error {
+	if !s.isRunning.Load() {
+		return nil
+	}
+	return s.notification("notifications/resources/updated", map[string]string{"uri": uri})
+}
@@ -0,0 +1,69 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchResource(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	var logs bytes.Buffer
+	server := NewServer(mockTransport, WithLogger(log.New(&logs, "", 0)))
+	require.NoError(t, server.Serve())
+
+	// The resource changes once, on the third check, and the check fails once
+	var checks atomic.Int32
+	server.WatchResource("file:///report.csv", 5*time.Millisecond, func() (bool, error) {
+		switch checks.Add(1) {
+		case 2:
+			return false, errors.New("backend unavailable")
+		case 3:
+			return true, nil
+		}
+		return false, nil
+	})
+
+	updates := func() []string {
+		var uris []string
+		for _, message := range mockTransport.GetMessages() {
+			if message.JsonRpcNotification != nil && message.JsonRpcNotification.Method == "notifications/resources/updated" {
+				uris = append(uris, string(message.JsonRpcNotification.Params))
+			}
+		}
+		return uris
+	}
+	require.Eventually(t, func() bool { return checks.Load() >= 6 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{`{"uri":"file:///report.csv"}`}, updates())
+	assert.Contains(t, logs.String(), "backend unavailable")
+
+	// Closing the server's transport stops the poller
+	require.NoError(t, mockTransport.Close())
+	time.Sleep(20 * time.Millisecond)
+	stoppedAt := checks.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, stoppedAt, checks.Load())
+}
+
+func TestWatchResourceBeforeServe(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+
+	// The poller runs while Serve starts, updates are only sent once the server is running
+	server.WatchResource("file:///report.csv", time.Millisecond, func() (bool, error) {
+		return true, nil
+	})
+	time.Sleep(5 * time.Millisecond)
+	assert.Empty(t, mockTransport.GetMessages())
+	require.NoError(t, server.Serve())
+
+	require.Eventually(t, func() bool { return len(mockTransport.GetMessages()) > 0 }, time.Second, time.Millisecond)
+	require.NoError(t, mockTransport.Close())
+}
@@ -1,13 +1,18 @@
 package mcp_golang
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/invopop/jsonschema"
 	"github.com/metoro-io/mcp-golang/internal/datastructures"
@@ -31,6 +36,19 @@ type toolResponseSent struct {
 
 // Custom JSON marshaling for ToolResponse
 func (c toolResponseSent) MarshalJSON() ([]byte, error) {
+	if c.Error == nil && c.Response.raw != nil {
+		if err := validateRawContent(c.Response.raw); err != nil {
+			c.Error = err
+		} else {
+			return json.Marshal(struct {
+				Content json.RawMessage `json:"content" yaml:"content" mapstructure:"content"`
+				IsError bool            `json:"isError" yaml:"isError" mapstructure:"isError"`
+			}{
+				Content: c.Response.raw,
+				IsError: c.Response.IsError,
+			})
+		}
+	}
 	if c.Error != nil {
 		errorText := c.Error.Error()
 		c.Response = NewToolResponse(NewTextContent(errorText))
@@ -39,18 +57,43 @@ func (c toolResponseSent) MarshalJSON() ([]byte, error) {
 		Content []*Content `json:"content" yaml:"content" mapstructure:"content"`
 		IsError bool       `json:"isError" yaml:"isError" mapstructure:"isError"`
 	}{
-		Content: c.Response.Content,
-		IsError: c.Error != nil,
+		Content: transcodeContents(c.Response.Content),
+		IsError: c.Error != nil || c.Response.IsError,
 	})
 }
 
+// StatusCode implements transport.StatusCoder so that transports can report ToolResponse.Status
+func (c toolResponseSent) StatusCode() int {
+	if c.Response == nil {
+		return 0
+	}
+	return c.Response.Status
+}
+
+// newToolResponseSentFromResult creates a toolResponseSent from the return values of a tool handler.
+// A response marked with IsError takes precedence over the error so that its content reaches the model.
+func newToolResponseSentFromResult(response *ToolResponse, err error) *toolResponseSent {
+	if response != nil && response.IsError {
+		return newToolResponseSent(response)
+	}
+	if err != nil {
+		return newToolResponseSentError(errors.Wrap(err, "handler returned an error"))
+	}
+	return newToolResponseSent(response)
+}
+
 // Custom JSON marshaling for ToolResponse
 func (c resourceResponseSent) MarshalJSON() ([]byte, error) {
 	if c.Error != nil {
 		errorText := c.Error.Error()
 		c.Response = NewResourceResponse(NewTextEmbeddedResource(c.Uri, errorText, "text/plain"))
 	}
-	return json.Marshal(c.Response)
+	if c.Response == nil {
+		return json.Marshal(c.Response)
+	}
+	response := *c.Response
+	response.Contents = transcodeEmbeddedResources(response.Contents)
+	return json.Marshal(&response)
 }
 
 type resourceResponseSent struct {
@@ -65,6 +108,18 @@ func newResourceResponseSentError(err error) *resourceResponseSent {
 	}
 }
 
+// ETag implements transport.ETagger so that transports can cache resource contents by ResourceResponse.ETag.
+// Errors and NotModified responses, which have no contents, can't be cached.
+func (c resourceResponseSent) ETag() (string, bool) {
+	if c.Error != nil || c.Response == nil || c.Response.NotModified {
+		return "", false
+	}
+	if c.Response.ETag == nil {
+		return "", true
+	}
+	return *c.Response.ETag, true
+}
+
 // newToolResponseSent creates a new toolResponseSent
 func newResourceResponseSent(response *ResourceResponse) *resourceResponseSent {
 	return &resourceResponseSent{
@@ -96,21 +151,78 @@ func (c promptResponseSent) MarshalJSON() ([]byte, error) {
 		errorText := c.Error.Error()
 		c.Response = NewPromptResponse("error", NewPromptMessage(NewTextContent(errorText), RoleUser))
 	}
-	return json.Marshal(c.Response)
+	if c.Response == nil {
+		return json.Marshal(c.Response)
+	}
+	response := *c.Response
+	response.Messages = make([]*PromptMessage, len(c.Response.Messages))
+	for i, message := range c.Response.Messages {
+		if message != nil {
+			message = &PromptMessage{Content: transcodeContent(message.Content), Role: message.Role}
+		}
+		response.Messages[i] = message
+	}
+	return json.Marshal(&response)
 }
 
 type Server struct {
-	isRunning          bool
-	transport          transport.Transport
-	protocol           *protocol.Protocol
-	paginationLimit    *int
-	tools              *datastructures.SyncMap[string, *tool]
-	prompts            *datastructures.SyncMap[string, *prompt]
-	resources          *datastructures.SyncMap[string, *resource]
-	resourceTemplates  *datastructures.SyncMap[string, *resourceTemplate]
+	// Read by notifications sent from any goroutine, e.g. WatchResource's poller
+	isRunning atomic.Bool
+	transport transport.Transport
+	protocol  *protocol.Protocol
+	// Transports added with AddTransport
+	additionalTransports []transport.Transport
+	// The session of each transport, the main transport's first. The others are added by Serve
+	sessions          []*session
+	paginationLimit   *int
+	tools             *datastructures.SyncMap[string, *tool]
+	prompts           *datastructures.SyncMap[string, *prompt]
+	resources         *datastructures.SyncMap[string, *resource]
+	resourceTemplates *datastructures.SyncMap[string, *resourceTemplate]
+	// Held for writing while tools, prompts or resources are registered or deregistered, so Describe sees a consistent snapshot
+	registryMu sync.RWMutex
+	// Set by WithStaticTools, toolsLocked is set once the server is serving
+	staticTools bool
+	toolsLocked bool
+	// Set by WithOverwrite, registrations replace existing ones with the same name
+	overwrite bool
+	// Set by WithResourceSingleflight, coalesces concurrent identical resource reads
+	resourceReads      *resourceReadGroup
 	serverInstructions *string
 	serverName         string
 	serverVersion      string
+	describeEndpoint   bool
+	toolCache          *toolCache
+	toolMiddleware     []ToolMiddleware
+	// Set by SetToolFallback, which can be called while serving
+	toolFallback atomic.Pointer[toolFallback]
+	// Set by SetResourceLister, which can be called while serving
+	resourceLister atomic.Pointer[resourceLister]
+	logger         Logger
+	logFile        *rotatingFile
+	decoder        Decoder
+	// Set by WithUseNumber
+	useNumber bool
+	// Set by WithMaxContentBlocks
+	maxContentBlocks int
+	// Set by WithListChangedDebounce
+	listChangedDebouncer *listChangedDebouncer
+	// Set by WithMethodTimeout and WithDefaultTimeout
+	methodTimeouts map[string]time.Duration
+	defaultTimeout time.Duration
+	// Set by Drain, new requests are rejected while it is
+	draining atomic.Bool
+	// Set by WithToolMaxArgBytes
+	toolMaxArgBytes map[string]int
+	// Handlers of custom methods registered with RegisterMethod, by method
+	methods sync.Map
+	// Set by WithCloseHandler
+	closeHandler func()
+	// Set by WithInstructionsFunc
+	instructionsFunc func(ctx context.Context) string
+	// Closed once all of the server's transports are closed, which stops background work such as WatchResource
+	done           chan struct{}
+	openTransports atomic.Int32
 }
 
 type prompt struct {
@@ -125,6 +237,14 @@ type tool struct {
 	Description     string
 	Handler         func(context.Context, baseCallToolRequestParams) *toolResponseSent
 	ToolInputSchema *jsonschema.Schema
+	// Listed instead of ToolInputSchema for tools registered from a user provided schema
+	RawInputSchema json.RawMessage
+	// Argument defaults of tools that have a RawInputSchema but no ToolInputSchema to validate against
+	DefaultsSchema *jsonschema.Schema
+	// Runs inside the server's global tool middleware
+	Middleware []ToolMiddleware
+	// Listed as the tool's _meta, set by WithToolMeta
+	Meta map[string]any
 }
 
 type resource struct {
@@ -170,12 +290,84 @@ func WithInstructions(instructions string) ServerOptions {
 	}
 }
 
+// WithInstructionsFunc computes the instructions sent to each client when it initializes, e.g. in the client's language
+// as read from HTTPHeaderFromContext(ctx). If it returns an empty string, the instructions set with WithInstructions are sent.
+func WithInstructionsFunc(instructionsFunc func(ctx context.Context) string) ServerOptions {
+	return func(s *Server) {
+		s.instructionsFunc = instructionsFunc
+	}
+}
+
 func WithVersion(version string) ServerOptions {
 	return func(s *Server) {
 		s.serverVersion = version
 	}
 }
 
+// WithDescribeEndpoint enables the server/describe request, which lists every JSON-RPC method the server handles.
+// It is off by default so that internal methods are not advertised.
+func WithDescribeEndpoint() ServerOptions {
+	return func(s *Server) {
+		s.describeEndpoint = true
+	}
+}
+
+// Errors returned when registering a tool, prompt or resource under a name or uri that is already registered,
+// on servers not created with WithOverwrite
+var (
+	ErrToolAlreadyRegistered     = errors.New("tool is already registered")
+	ErrPromptAlreadyRegistered   = errors.New("prompt is already registered")
+	ErrResourceAlreadyRegistered = errors.New("resource is already registered")
+)
+
+// WithOverwrite lets registering a tool, prompt or resource replace one that is already registered under the same name or uri.
+// Without it registering fails with ErrToolAlreadyRegistered, ErrPromptAlreadyRegistered or ErrResourceAlreadyRegistered,
+// to catch accidental name collisions.
+func WithOverwrite() ServerOptions {
+	return func(s *Server) {
+		s.overwrite = true
+	}
+}
+
+// ErrStaticTools is returned when registering or deregistering a tool after Serve on a server created with WithStaticTools
+var ErrStaticTools = errors.New("tools can't be changed after the server started, as it was created with WithStaticTools")
+
+// WithStaticTools locks the server's tools once it starts serving, so that registering or deregistering a tool fails with ErrStaticTools.
+// The server then advertises that its tool list doesn't change, rather than that clients are notified when it does.
+func WithStaticTools() ServerOptions {
+	return func(s *Server) {
+		s.staticTools = true
+	}
+}
+
+// WithCloseHandler sets a callback for when a transport the server serves on closes,
+// e.g. when the client of a stdio server closes its input. It can be used to exit the process.
+func WithCloseHandler(handler func()) ServerOptions {
+	return func(s *Server) {
+		s.closeHandler = handler
+	}
+}
+
+// WithToolMaxArgBytes limits the size of the JSON arguments of the given tool to n bytes.
+// Calls with bigger arguments are rejected with an invalid params error before the arguments are decoded.
+func WithToolMaxArgBytes(toolName string, n int) ServerOptions {
+	return func(s *Server) {
+		if s.toolMaxArgBytes == nil {
+			s.toolMaxArgBytes = make(map[string]int)
+		}
+		s.toolMaxArgBytes[toolName] = n
+	}
+}
+
+// WithToolCache caches the responses of the given tool for ttl, keyed by its arguments.
+// Only use it for tools whose result depends on nothing but their arguments. Error responses are never cached.
+// At most 1024 responses are cached across all tools, the least recently used ones are evicted first.
+func WithToolCache(toolName string, ttl time.Duration) ServerOptions {
+	return func(s *Server) {
+		s.toolCache.enable(toolName, ttl)
+	}
+}
+
 func NewServer(transport transport.Transport, options ...ServerOptions) *Server {
 	server := &Server{
 		protocol:          protocol.NewProtocol(nil),
@@ -184,36 +376,244 @@ func NewServer(transport transport.Transport, options ...ServerOptions) *Server
 		prompts:           new(datastructures.SyncMap[string, *prompt]),
 		resources:         new(datastructures.SyncMap[string, *resource]),
 		resourceTemplates: new(datastructures.SyncMap[string, *resourceTemplate]),
+		toolCache:         newToolCache(),
+		maxContentBlocks:  defaultMaxContentBlocks,
+		done:              make(chan struct{}),
 	}
 	for _, option := range options {
 		option(server)
 	}
+	server.sessions = []*session{{protocol: server.protocol}}
 	return server
 }
 
 // RegisterTool registers a new tool with the server
-func (s *Server) RegisterTool(name string, description string, handler any) error {
+func (s *Server) RegisterTool(name string, description string, handler any, options ...ToolOption) error {
 	err := validateToolHandler(handler)
 	if err != nil {
 		return err
 	}
 	inputSchema := createJsonSchemaFromHandler(handler)
 
-	s.tools.Store(name, &tool{
+	t := &tool{
 		Name:            name,
 		Description:     description,
-		Handler:         createWrappedToolHandler(handler),
+		Handler:         createWrappedToolHandler(handler, s.decodeArguments),
 		ToolInputSchema: inputSchema,
+	}
+	for _, option := range options {
+		option(t)
+	}
+	if err := s.storeTool(t); err != nil {
+		return err
+	}
+
+	return s.sendToolListChangedNotification()
+}
+
+// RegisterFunc registers a Go function as a tool. The function takes a struct of arguments, optionally preceded by a context.Context,
+// and returns either (*ToolResponse, error) or (T, error) for any other type T.
+// A T result is sent as text content: strings as they are, anything else as JSON.
+// An error is returned if the function's signature is not supported.
+func (s *Server) RegisterFunc(name string, description string, fn any, options ...ToolOption) error {
+	handler, err := wrapFunc(fn)
+	if err != nil {
+		return errors.Wrapf(err, "unsupported function for tool %s", name)
+	}
+	return s.RegisterTool(name, description, handler, options...)
+}
+
+// wrapFunc adapts a function returning (T, error) into a tool handler returning (*ToolResponse, error)
+func wrapFunc(fn any) (any, error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return nil, fmt.Errorf("expected a function, got %T", fn)
+	}
+	fnType := fnValue.Type()
+	if fnType.NumIn() != 1 && fnType.NumIn() != 2 {
+		return nil, fmt.Errorf("function must take an argument struct, optionally preceded by context.Context, got %d arguments", fnType.NumIn())
+	}
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	if fnType.NumIn() == 2 && fnType.In(0) != contextType {
+		return nil, fmt.Errorf("first of two arguments must be context.Context, got %s", fnType.In(0))
+	}
+	if argumentType := fnType.In(fnType.NumIn() - 1); argumentType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("arguments must be a struct, got %s", argumentType)
+	}
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if fnType.NumOut() != 2 || fnType.Out(1) != errorType {
+		return nil, fmt.Errorf("function must return a result and an error")
+	}
+
+	toolResponseType := reflect.TypeOf(&ToolResponse{})
+	if fnType.Out(0) == toolResponseType {
+		return fn, nil
+	}
+	inTypes := make([]reflect.Type, fnType.NumIn())
+	for i := range inTypes {
+		inTypes[i] = fnType.In(i)
+	}
+	handlerType := reflect.FuncOf(inTypes, []reflect.Type{toolResponseType, errorType}, false)
+	handler := reflect.MakeFunc(handlerType, func(args []reflect.Value) []reflect.Value {
+		output := fnValue.Call(args)
+		var response *ToolResponse
+		err, _ := output[1].Interface().(error)
+		if err == nil {
+			response, err = toolResponseFromResult(output[0].Interface())
+		}
+		errValue := reflect.Zero(errorType)
+		if err != nil {
+			errValue = reflect.ValueOf(&err).Elem()
+		}
+		return []reflect.Value{reflect.ValueOf(response), errValue}
 	})
+	return handler.Interface(), nil
+}
+
+// toolResponseFromResult sends a string result as is and any other result as JSON text
+func toolResponseFromResult(result any) (*ToolResponse, error) {
+	if text, ok := result.(string); ok {
+		return NewToolResponse(NewTextContent(text)), nil
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal result")
+	}
+	return NewToolResponse(NewTextContent(string(encoded))), nil
+}
+
+// RegisterToolFromSchema registers a new tool whose input schema is provided directly rather than generated from a Go struct.
+// The schema is returned as-is from tools/list and the handler receives the raw JSON arguments of each call.
+// This is useful for tools that are defined at runtime, e.g. from JSON schema files or plugins.
+func (s *Server) RegisterToolFromSchema(name string, description string, inputSchema json.RawMessage, handler func(args json.RawMessage) (*ToolResponse, error), options ...ToolOption) error {
+	if handler == nil {
+		return fmt.Errorf("handler must not be nil")
+	}
+	var schemaObject map[string]interface{}
+	if err := json.Unmarshal(inputSchema, &schemaObject); err != nil {
+		return errors.Wrap(err, "input schema must be a JSON object")
+	}
+	// Only used for defaults, so a schema the jsonschema package can't represent is not an error
+	var defaultsSchema *jsonschema.Schema
+	if err := json.Unmarshal(inputSchema, &defaultsSchema); err != nil {
+		defaultsSchema = nil
+	}
+
+	t := &tool{
+		Name:        name,
+		Description: description,
+		Handler: func(ctx context.Context, params baseCallToolRequestParams) *toolResponseSent {
+			arguments := params.Arguments
+			if len(arguments) == 0 {
+				arguments = json.RawMessage("{}")
+			}
+			return newToolResponseSentFromResult(handler(arguments))
+		},
+		RawInputSchema: inputSchema,
+		DefaultsSchema: defaultsSchema,
+	}
+	for _, option := range options {
+		option(t)
+	}
+	if err := s.storeTool(t); err != nil {
+		return err
+	}
+
+	return s.sendToolListChangedNotification()
+}
+
+// RegisterDynamicTool registers a new tool with a user provided input schema whose handler receives the arguments as a map,
+// for tools whose arguments don't fit a Go struct, e.g. plugins. Arguments are validated against the schema before the handler is called.
+func (s *Server) RegisterDynamicTool(name string, description string, inputSchema json.RawMessage, handler func(args map[string]any) (*ToolResponse, error), options ...ToolOption) error {
+	if handler == nil {
+		return fmt.Errorf("handler must not be nil")
+	}
+	schema := &jsonschema.Schema{}
+	if err := json.Unmarshal(inputSchema, schema); err != nil {
+		return errors.Wrap(err, "input schema must be a JSON schema object")
+	}
+	if schema.Type != "" && schema.Type != "object" {
+		return fmt.Errorf("input schema must be of type object, got %s", schema.Type)
+	}
+
+	t := &tool{
+		Name:        name,
+		Description: description,
+		Handler: func(ctx context.Context, params baseCallToolRequestParams) *toolResponseSent {
+			args := make(map[string]any)
+			if len(params.Arguments) > 0 && string(params.Arguments) != "null" {
+				if err := s.decodeArguments(params.Arguments, &args); err != nil {
+					return newToolResponseSentError(&argumentsError{err: err})
+				}
+			}
+			return newToolResponseSentFromResult(handler(args))
+		},
+		ToolInputSchema: schema,
+		RawInputSchema:  inputSchema,
+	}
+	for _, option := range options {
+		option(t)
+	}
+	if err := s.storeTool(t); err != nil {
+		return err
+	}
 
 	return s.sendToolListChangedNotification()
 }
 
+// toolFallback handles tools/call requests for tools that aren't registered, see SetToolFallback
+type toolFallback func(ctx context.Context, name string, args json.RawMessage) (*ToolResponse, error)
+
+// SetToolFallback sets a handler for tools/call requests that name a tool that isn't registered,
+// e.g. to forward them to a backend server. Tools served by the fallback are not listed by tools/list.
+// Global tool middleware still runs around the fallback. It can be changed while serving, nil removes the fallback.
+func (s *Server) SetToolFallback(fallback func(ctx context.Context, name string, args json.RawMessage) (*ToolResponse, error)) {
+	if fallback == nil {
+		s.toolFallback.Store(nil)
+		return
+	}
+	f := toolFallback(fallback)
+	s.toolFallback.Store(&f)
+}
+
+// fallbackTool returns a tool that serves the named tool with the fallback handler
+func fallbackTool(name string, fallback toolFallback) *tool {
+	return &tool{
+		Name: name,
+		Handler: func(ctx context.Context, params baseCallToolRequestParams) *toolResponseSent {
+			return newToolResponseSentFromResult(fallback(ctx, params.Name, params.Arguments))
+		},
+	}
+}
+
 func (s *Server) sendToolListChangedNotification() error {
-	if !s.isRunning {
+	if !s.isRunning.Load() {
+		return nil
+	}
+	return s.listChanged("notifications/tools/list_changed")
+}
+
+// listChanged sends a list_changed notification, or schedules it if WithListChangedDebounce is set
+func (s *Server) listChanged(method string) error {
+	if s.listChangedDebouncer != nil {
+		s.listChangedDebouncer.notify(method)
 		return nil
 	}
-	return s.protocol.Notification("notifications/tools/list_changed", nil)
+	return s.notification(method, nil)
+}
+
+// notification sends a notification to the clients of every transport the server is serving
+func (s *Server) notification(method string, params interface{}) error {
+	var errs []string
+	for _, sess := range s.sessions {
+		if err := sess.protocol.Notification(method, params); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send %s notification: %s", method, strings.Join(errs, "; "))
+	}
+	return nil
 }
 
 func (s *Server) CheckToolRegistered(name string) bool {
@@ -221,16 +621,56 @@ func (s *Server) CheckToolRegistered(name string) bool {
 	return ok
 }
 
+// DisableToolCache marks the tool as non-cacheable and drops its cached responses
+func (s *Server) DisableToolCache(name string) {
+	s.toolCache.enable(name, 0)
+}
+
+// storeTool adds a tool to the registry, unless the server's tools are static and it is already serving.
+// A tool with the same name is only replaced on servers created with WithOverwrite.
+func (s *Server) storeTool(t *tool) error {
+	s.registryMu.Lock()
+	if s.toolsLocked {
+		s.registryMu.Unlock()
+		return ErrStaticTools
+	}
+	if _, ok := s.tools.Load(t.Name); ok && !s.overwrite {
+		s.registryMu.Unlock()
+		return errors.Wrap(ErrToolAlreadyRegistered, t.Name)
+	}
+	s.tools.Store(t.Name, t)
+	s.registryMu.Unlock()
+	s.toolCache.invalidate(t.Name)
+	return nil
+}
+
 func (s *Server) DeregisterTool(name string) error {
+	s.registryMu.Lock()
+	if s.toolsLocked {
+		s.registryMu.Unlock()
+		return ErrStaticTools
+	}
 	s.tools.Delete(name)
+	s.registryMu.Unlock()
+	s.toolCache.invalidate(name)
 	return s.sendToolListChangedNotification()
 }
 
+// RegisterResource registers a resource that is read by calling handler.
+// uri must be an absolute URI with a scheme, e.g. file:///readme.md, that isn't registered yet.
 func (s *Server) RegisterResource(uri string, name string, description string, mimeType string, handler any) error {
 	err := validateResourceHandler(handler)
 	if err != nil {
 		panic(err)
 	}
+	if err := validateResourceUri(uri); err != nil {
+		return err
+	}
+	s.registryMu.Lock()
+	if _, ok := s.resources.Load(uri); ok && !s.overwrite {
+		s.registryMu.Unlock()
+		return errors.Wrap(ErrResourceAlreadyRegistered, uri)
+	}
 	s.resources.Store(uri, &resource{
 		Name:        name,
 		Description: description,
@@ -238,14 +678,46 @@ func (s *Server) RegisterResource(uri string, name string, description string, m
 		mimeType:    mimeType,
 		Handler:     createWrappedResourceHandler(handler),
 	})
+	s.registryMu.Unlock()
 	return s.sendResourceListChangedNotification()
 }
 
+// validateResourceUri returns an error if uri can't be parsed or has no scheme, as a client could never read such a resource
+func validateResourceUri(uri string) error {
+	if uri == "" {
+		return errors.New("resource uri is empty")
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return errors.Wrapf(err, "invalid resource uri %s", uri)
+	}
+	if parsed.Scheme == "" {
+		return errors.Errorf("resource uri %s has no scheme", uri)
+	}
+	return nil
+}
+
+// resourceLister lists resources for resources/list requests, see SetResourceLister
+type resourceLister func(ctx context.Context, cursor *string) (*ListResourcesResponse, error)
+
+// SetResourceLister replaces the static resource registry for resources/list with a function that lists resources per request,
+// e.g. from a database or filtered by the authenticated user. The lister is responsible for pagination.
+// Resources registered with RegisterResource can still be read, and are listed again once the lister is set to nil.
+// It can be changed while serving.
+func (s *Server) SetResourceLister(lister func(ctx context.Context, cursor *string) (*ListResourcesResponse, error)) {
+	if lister == nil {
+		s.resourceLister.Store(nil)
+		return
+	}
+	l := resourceLister(lister)
+	s.resourceLister.Store(&l)
+}
+
 func (s *Server) sendResourceListChangedNotification() error {
-	if !s.isRunning {
+	if !s.isRunning.Load() {
 		return nil
 	}
-	return s.protocol.Notification("notifications/resources/list_changed", nil)
+	return s.listChanged("notifications/resources/list_changed")
 }
 
 func (s *Server) CheckResourceRegistered(uri string) bool {
@@ -254,7 +726,9 @@ func (s *Server) CheckResourceRegistered(uri string) bool {
 }
 
 func (s *Server) DeregisterResource(uri string) error {
+	s.registryMu.Lock()
 	s.resources.Delete(uri)
+	s.registryMu.Unlock()
 	return s.sendResourceListChangedNotification()
 }
 
@@ -335,27 +809,147 @@ func (s *Server) DeregisterResourceTemplate(uriTemplate string) error {
 	return s.sendResourceListChangedNotification()
 }
 
+// storePrompt adds a prompt to the registry. A prompt with the same name is only replaced on servers created with WithOverwrite.
+func (s *Server) storePrompt(p *prompt) error {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+	if _, ok := s.prompts.Load(p.Name); ok && !s.overwrite {
+		return errors.Wrap(ErrPromptAlreadyRegistered, p.Name)
+	}
+	s.prompts.Store(p.Name, p)
+	return nil
+}
+
 func (s *Server) RegisterPrompt(name string, description string, handler any) error {
 	err := validatePromptHandler(handler)
 	if err != nil {
 		return err
 	}
 	promptSchema := createPromptSchemaFromHandler(handler)
-	s.prompts.Store(name, &prompt{
+	if err := s.storePrompt(&prompt{
 		Name:              name,
 		Description:       description,
-		Handler:           createWrappedPromptHandler(handler),
+		Handler:           createWrappedPromptHandler(handler, s.decodeArguments),
 		PromptInputSchema: promptSchema,
-	})
+	}); err != nil {
+		return err
+	}
 
 	return s.sendPromptListChangedNotification()
 }
 
+// RegisterPromptTyped registers a new prompt whose arguments are decoded into T.
+// T must be a struct with only string or *string fields, tagged the same way as for RegisterPrompt.
+// The argument metadata is generated once at registration, and required arguments are checked before the handler is called.
+func RegisterPromptTyped[T any](s *Server, name string, description string, handler func(args T) (*PromptResponse, error)) error {
+	if handler == nil {
+		return fmt.Errorf("handler must not be nil")
+	}
+	argumentType := reflect.TypeOf((*T)(nil)).Elem()
+	if err := validatePromptArgumentType(argumentType); err != nil {
+		return err
+	}
+	promptSchema := createPromptSchemaFromType(argumentType)
+
+	if err := s.storePrompt(&prompt{
+		Name:        name,
+		Description: description,
+		Handler: func(ctx context.Context, params baseGetPromptRequestParamsArguments) *promptResponseSent {
+			var args T
+			if len(params.Arguments) > 0 {
+				if err := s.decodeArguments(params.Arguments, &args); err != nil {
+					return newPromptResponseSentError(errors.Wrap(err, "failed to unmarshal arguments"))
+				}
+			}
+			argsValue := reflect.ValueOf(args)
+			for i, argument := range promptSchema.Arguments {
+				if *argument.Required && argsValue.Field(i).IsZero() {
+					return newPromptResponseSentError(fmt.Errorf("missing required argument %s", argument.Name))
+				}
+			}
+			response, err := handler(args)
+			if err != nil {
+				return newPromptResponseSentError(err)
+			}
+			return newPromptResponseSent(response)
+		},
+		PromptInputSchema: promptSchema,
+	}); err != nil {
+		return err
+	}
+
+	return s.sendPromptListChangedNotification()
+}
+
+// RegisterPromptRaw registers a new prompt whose handler receives the raw JSON arguments of each request, e.g. to pass them through to a template.
+// The arguments listed in prompts/list are the properties of argsSchema, a JSON schema object, along with their descriptions and whether they are required.
+func (s *Server) RegisterPromptRaw(name string, description string, argsSchema json.RawMessage, handler func(args json.RawMessage) (*PromptResponse, error)) error {
+	if handler == nil {
+		return fmt.Errorf("handler must not be nil")
+	}
+	promptSchema, err := createPromptSchemaFromJSONSchema(argsSchema)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storePrompt(&prompt{
+		Name:        name,
+		Description: description,
+		Handler: func(ctx context.Context, params baseGetPromptRequestParamsArguments) *promptResponseSent {
+			arguments := params.Arguments
+			if len(arguments) == 0 {
+				arguments = json.RawMessage("{}")
+			}
+			response, err := handler(arguments)
+			if err != nil {
+				return newPromptResponseSentError(err)
+			}
+			return newPromptResponseSent(response)
+		},
+		PromptInputSchema: promptSchema,
+	}); err != nil {
+		return err
+	}
+
+	return s.sendPromptListChangedNotification()
+}
+
+// createPromptSchemaFromJSONSchema lists the properties of a JSON schema object as prompt arguments, in the order they are defined
+func createPromptSchemaFromJSONSchema(argsSchema json.RawMessage) (*PromptSchema, error) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(argsSchema, &schema); err != nil {
+		return nil, errors.Wrap(err, "arguments schema must be a JSON schema object")
+	}
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	promptSchema := &PromptSchema{
+		Arguments: make([]PromptSchemaArgument, 0),
+	}
+	if schema.Properties == nil {
+		return promptSchema, nil
+	}
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		argument := PromptSchemaArgument{
+			Name:     pair.Key,
+			Required: new(bool),
+		}
+		*argument.Required = required[pair.Key]
+		if pair.Value.Description != "" {
+			description := pair.Value.Description
+			argument.Description = &description
+		}
+		promptSchema.Arguments = append(promptSchema.Arguments, argument)
+	}
+	return promptSchema, nil
+}
+
 func (s *Server) sendPromptListChangedNotification() error {
-	if !s.isRunning {
+	if !s.isRunning.Load() {
 		return nil
 	}
-	return s.protocol.Notification("notifications/prompts/list_changed", nil)
+	return s.listChanged("notifications/prompts/list_changed")
 }
 
 func (s *Server) CheckPromptRegistered(name string) bool {
@@ -364,11 +958,13 @@ func (s *Server) CheckPromptRegistered(name string) bool {
 }
 
 func (s *Server) DeregisterPrompt(name string) error {
+	s.registryMu.Lock()
 	s.prompts.Delete(name)
+	s.registryMu.Unlock()
 	return s.sendPromptListChangedNotification()
 }
 
-func createWrappedPromptHandler(userHandler any) func(context.Context, baseGetPromptRequestParamsArguments) *promptResponseSent {
+func createWrappedPromptHandler(userHandler any, decode func(data []byte, v any) error) func(context.Context, baseGetPromptRequestParamsArguments) *promptResponseSent {
 	handlerValue := reflect.ValueOf(userHandler)
 	handlerType := handlerValue.Type()
 	var argumentType reflect.Type
@@ -384,8 +980,8 @@ func createWrappedPromptHandler(userHandler any) func(context.Context, baseGetPr
 		}
 		unmarshaledArguments := reflect.New(argumentType).Interface()
 
-		// Unmarshal the JSON into the correct type
-		err := json.Unmarshal(arguments.Arguments, &unmarshaledArguments)
+		// Decode the arguments into the correct type
+		err := decode(arguments.Arguments, unmarshaledArguments)
 		if err != nil {
 			return newPromptResponseSentError(errors.Wrap(err, "failed to unmarshal arguments"))
 		}
@@ -434,8 +1030,11 @@ func createWrappedPromptHandler(userHandler any) func(context.Context, baseGetPr
 func createPromptSchemaFromHandler(handler any) *PromptSchema {
 	handlerValue := reflect.ValueOf(handler)
 	handlerType := handlerValue.Type()
-	argumentType := handlerType.In(0)
+	return createPromptSchemaFromType(handlerType.In(0))
+}
 
+// createPromptSchemaFromType creates the prompt argument metadata from the fields of the argument struct
+func createPromptSchemaFromType(argumentType reflect.Type) *PromptSchema {
 	promptSchema := PromptSchema{
 		Arguments: make([]PromptSchemaArgument, argumentType.NumField()),
 	}
@@ -483,6 +1082,11 @@ func validatePromptHandler(handler any) error {
 		return fmt.Errorf("handler must take one or two arguments, got %d", handlerType.NumIn())
 	}
 
+	return validatePromptArgumentType(argumentType)
+}
+
+// validatePromptArgumentType checks that the argument is a struct with only string or *string fields
+func validatePromptArgumentType(argumentType reflect.Type) error {
 	if argumentType.Kind() != reflect.Struct {
 		return fmt.Errorf("argument must be a struct")
 	}
@@ -513,14 +1117,26 @@ func createJsonSchemaFromHandler(handler any) *jsonschema.Schema {
 	} else if handlerType.NumIn() == 1 {
 		argumentType = handlerType.In(0)
 	}
-	inputSchema := jsonSchemaReflector.ReflectFromType(argumentType)
-	return inputSchema
+	return reflectJsonSchema(argumentType)
+}
+
+// jsonSchemaCache holds the schema generated for each argument type, keyed by reflect.Type.
+// Schemas are never modified once generated, so tools with the same argument type share one.
+var jsonSchemaCache sync.Map
+
+// reflectJsonSchema returns the schema of argumentType, generating it only the first time it is asked for
+func reflectJsonSchema(argumentType reflect.Type) *jsonschema.Schema {
+	if schema, ok := jsonSchemaCache.Load(argumentType); ok {
+		return schema.(*jsonschema.Schema)
+	}
+	schema, _ := jsonSchemaCache.LoadOrStore(argumentType, jsonSchemaReflector.ReflectFromType(argumentType))
+	return schema.(*jsonschema.Schema)
 }
 
 // This takes a user provided handler and returns a wrapped handler which can be used to actually answer requests
 // Concretely, it will deserialize the arguments and call the user provided handler and then serialize the response
 // If the handler returns an error, it will be serialized and sent back as a tool error rather than a protocol error
-func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToolRequestParams) *toolResponseSent {
+func createWrappedToolHandler(userHandler any, decode func(data []byte, v any) error) func(context.Context, baseCallToolRequestParams) *toolResponseSent {
 	handlerValue := reflect.ValueOf(userHandler)
 	handlerType := handlerValue.Type()
 	var argumentType reflect.Type
@@ -536,10 +1152,10 @@ func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToo
 		}
 		unmarshaledArguments := reflect.New(argumentType).Interface()
 
-		// Unmarshal the JSON into the correct type
-		err := json.Unmarshal(arguments.Arguments, &unmarshaledArguments)
+		// Decode the arguments into the correct type
+		err := decode(arguments.Arguments, unmarshaledArguments)
 		if err != nil {
-			return newToolResponseSentError(errors.Wrap(err, "failed to unmarshal arguments"))
+			return newToolResponseSentError(&argumentsError{err: err})
 		}
 
 		// Need to dereference the unmarshaled arguments
@@ -565,47 +1181,208 @@ func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToo
 		if !output[0].CanInterface() {
 			return newToolResponseSentError(errors.Wrap(fmt.Errorf("handler must return a struct, got %s", output[0].Type().Name()), "invalid handler return"))
 		}
-		tool := output[0].Interface()
+		tool, _ := output[0].Interface().(*ToolResponse)
 		if !output[1].CanInterface() {
 			return newToolResponseSentError(errors.Wrap(fmt.Errorf("handler must return an error, got %s", output[1].Type().Name()), "invalid handler return"))
 		}
-		errorOut := output[1].Interface()
-		if errorOut == nil {
-			return newToolResponseSent(tool.(*ToolResponse))
-		}
-		return newToolResponseSentError(errors.Wrap(errorOut.(error), "handler returned an error"))
+		errorOut, _ := output[1].Interface().(error)
+		return newToolResponseSentFromResult(tool, errorOut)
 	}
 }
 
+// SendLogMessageNotification sends a notifications/message log message to the client with a string as its data
+func (s *Server) SendLogMessageNotification(level Level, logger string, message string) error {
+	return s.sendLogMessageNotification(level, logger, message)
+}
+
+// SendStructuredLogNotification sends a notifications/message log message to the client.
+// The fields are sent as a JSON object in the data of the message so that their structure is preserved.
+func (s *Server) SendStructuredLogNotification(level Level, logger string, fields map[string]any) error {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	return s.sendLogMessageNotification(level, logger, fields)
+}
+
+func (s *Server) sendLogMessageNotification(level Level, logger string, data any) error {
+	if !level.Valid() {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	if !s.isRunning.Load() {
+		return fmt.Errorf("server is not running")
+	}
+	notification, err := newLogMessageNotification(level, logger, data)
+	if err != nil {
+		return err
+	}
+	return s.notification("notifications/message", notification)
+}
+
+// newLogMessageNotification creates the payload of a notifications/message notification with data marshalled as JSON
+func newLogMessageNotification(level Level, logger string, data any) (*LoggingMessageNotification, error) {
+	marshalledData, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal log message data")
+	}
+	notification := &LoggingMessageNotification{
+		Level: level,
+		Data:  marshalledData,
+	}
+	if logger != "" {
+		notification.Logger = &logger
+	}
+	return notification, nil
+}
+
+// AddTransport makes the server reachable over an additional transport, e.g. HTTP alongside stdio.
+// All transports share the server's tools, prompts and resources, but each has its own session with its clients.
+// Transports must be added before calling Serve.
+func (s *Server) AddTransport(transport transport.Transport) error {
+	if s.isRunning.Load() {
+		return fmt.Errorf("cannot add a transport to a running server")
+	}
+	s.additionalTransports = append(s.additionalTransports, transport)
+	return nil
+}
+
+// Serve starts the server on all of its transports.
+// Serve returns once every transport has been started, so it blocks for as long as a transport's Start blocks, as with the HTTP transport.
+// If a transport fails to start, its error is returned and the other transports are closed.
+// Clients only use the kinds of things, tools, prompts or resources, that the server has when they initialize, so register
+// at least one of each kind the server offers before calling Serve. Later changes are announced with list_changed notifications.
 func (s *Server) Serve() error {
-	if s.isRunning {
+	if s.isRunning.Load() {
 		return fmt.Errorf("server is already running")
 	}
-	pr := s.protocol
-	pr.SetRequestHandler("ping", s.handlePing)
-	pr.SetRequestHandler("initialize", s.handleInitialize)
+	if s.staticTools {
+		s.registryMu.Lock()
+		s.toolsLocked = true
+		s.registryMu.Unlock()
+	}
+	s.openTransports.Store(int32(1 + len(s.additionalTransports)))
+	s.registerHandlers(s.sessions[0])
+	if len(s.additionalTransports) == 0 {
+		err := s.protocol.Connect(s.transport)
+		if err != nil {
+			return err
+		}
+		s.isRunning.Store(true)
+		return nil
+	}
+
+	transports := append([]transport.Transport{s.transport}, s.additionalTransports...)
+	for range s.additionalTransports {
+		sess := &session{protocol: protocol.NewProtocol(nil)}
+		s.registerHandlers(sess)
+		s.sessions = append(s.sessions, sess)
+	}
+	s.isRunning.Store(true)
+
+	type connectResult struct {
+		index int
+		err   error
+	}
+	results := make(chan connectResult, len(transports))
+	for i := range transports {
+		go func(i int) {
+			results <- connectResult{index: i, err: s.sessions[i].protocol.Connect(transports[i])}
+		}(i)
+	}
+	for range transports {
+		result := <-results
+		if result.err == nil {
+			continue
+		}
+		s.isRunning.Store(false)
+		for i, tr := range transports {
+			if i != result.index {
+				tr.Close()
+			}
+		}
+		return result.err
+	}
+	return nil
+}
+
+// transportClosed stops the server's background work once the last of its transports is closed
+func (s *Server) transportClosed() {
+	if s.openTransports.Add(-1) == 0 {
+		close(s.done)
+		if s.logFile != nil {
+			s.logFile.Close()
+		}
+	}
+}
+
+func (s *Server) registerHandlers(sess *session) {
+	pr := sess.protocol
+	if s.logger != nil {
+		pr.Logf = s.logger.Printf
+	}
+	pr.WithInboundInterceptor(normalizeRequestParams)
+	var closeOnce sync.Once
+	pr.OnClose = func() {
+		closeOnce.Do(s.transportClosed)
+		if s.closeHandler != nil {
+			s.closeHandler()
+		}
+	}
+	handle := func(method string, handler requestHandler) {
+		pr.SetRequestHandler(method, withSession(sess, s.withDrain(s.withTimeout(method, s.withContentLimit(method, s.withRequestLogger(pr, handler))))))
+	}
+	handle("ping", s.handlePing)
+	handle("initialize", s.handleInitialize)
 	pr.SetNotificationHandler("notifications/initialized", s.handleNotificationsInitialize)
-	pr.SetRequestHandler("tools/list", s.handleListTools)
-	pr.SetRequestHandler("tools/call", s.handleToolCalls)
-	pr.SetRequestHandler("prompts/list", s.handleListPrompts)
-	pr.SetRequestHandler("prompts/get", s.handlePromptCalls)
-	pr.SetRequestHandler("resources/list", s.handleListResources)
-	pr.SetRequestHandler("resources/templates/list", s.handleListResourceTemplates)
-	pr.SetRequestHandler("resources/read", s.handleResourceCalls)
-	err := pr.Connect(s.transport)
-	if err != nil {
-		return err
+	handle("tools/list", s.handleListTools)
+	handle("tools/call", s.handleToolCalls)
+	handle("prompts/list", s.handleListPrompts)
+	handle("prompts/get", s.handlePromptCalls)
+	handle("resources/list", s.handleListResources)
+	handle("resources/templates/list", s.handleListResourceTemplates)
+	handle("resources/read", s.handleResourceCalls)
+	if s.describeEndpoint {
+		handle("server/describe", s.handleDescribe)
+	}
+	s.withCustomMethods(sess)
+}
+
+// normalizeRequestParams replaces absent or null request params with an empty object,
+// so that handlers don't have to tell them apart from a request without any parameters set
+func normalizeRequestParams(message *transport.BaseJsonRpcMessage) error {
+	if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+		return nil
+	}
+	params := bytes.TrimSpace(message.JsonRpcRequest.Params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		message.JsonRpcRequest.Params = json.RawMessage("{}")
 	}
-	s.protocol = pr
-	s.isRunning = true
 	return nil
 }
 
 func (s *Server) handleInitialize(ctx context.Context, request *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	var params struct {
+		Capabilities struct {
+			Elicitation json.RawMessage `json:"elicitation"`
+		} `json:"capabilities"`
+	}
+	if len(request.Params) > 0 {
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal initialize params"))
+		}
+	}
+	s.session(ctx).clientElicitation.Store(params.Capabilities.Elicitation != nil && string(params.Capabilities.Elicitation) != "null")
+
+	instructions := s.serverInstructions
+	if s.instructionsFunc != nil {
+		if localized := s.instructionsFunc(ctx); localized != "" {
+			instructions = &localized
+		}
+	}
+
 	return InitializeResponse{
 		Meta:            nil,
 		Capabilities:    s.generateCapabilities(),
-		Instructions:    s.serverInstructions,
+		Instructions:    instructions,
 		ProtocolVersion: "2024-11-05",
 		ServerInfo: implementation{
 			Name:    s.serverName,
@@ -628,7 +1405,7 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 	if request.Params != nil {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
 		}
 	}
 
@@ -647,7 +1424,7 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to decode cursor"))
 		}
 		cString := string(c)
 		// Iterate through the tools until we find an entry > the cursor
@@ -674,10 +1451,15 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 	toolsToReturn := make([]ToolRetType, 0)
 
 	for i := startPosition; i < endPosition; i++ {
+		var inputSchema interface{} = orderedTools[i].ToolInputSchema
+		if orderedTools[i].RawInputSchema != nil {
+			inputSchema = orderedTools[i].RawInputSchema
+		}
 		toolsToReturn = append(toolsToReturn, ToolRetType{
 			Name:        orderedTools[i].Name,
 			Description: &orderedTools[i].Description,
-			InputSchema: orderedTools[i].ToolInputSchema,
+			InputSchema: inputSchema,
+			Meta:        orderedTools[i].Meta,
 		})
 	}
 
@@ -690,15 +1472,27 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 			}
 			return nil
 		}(),
+		Total: s.paginationTotal(len(orderedTools)),
 	}, nil
 }
 
+// paginationTotal returns the total to list with a page of items, or nil if the server doesn't paginate
+func (s *Server) paginationTotal(total int) *int {
+	if s.paginationLimit == nil {
+		return nil
+	}
+	return &total
+}
+
 func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
 	params := baseCallToolRequestParams{}
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
+	}
+	if limit, ok := s.toolMaxArgBytes[params.Name]; ok && len(params.Arguments) > limit {
+		return nil, newInvalidParamsError(fmt.Errorf("arguments of tool %s are %d bytes, more than the limit of %d", params.Name, len(params.Arguments), limit))
 	}
 
 	var toolToUse *tool
@@ -710,31 +1504,119 @@ func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPC
 		return false
 	})
 
+	if fallback := s.toolFallback.Load(); toolToUse == nil && fallback != nil {
+		toolToUse = fallbackTool(params.Name, *fallback)
+	}
 	if toolToUse == nil {
-		return nil, errors.Wrapf(err, "unknown tool: %s", req.Method)
+		return nil, newInvalidParamsError(fmt.Errorf("unknown tool: %s", params.Name))
+	}
+	// Clients may leave out arguments that have a default in the schema they were listed with
+	if toolToUse.ToolInputSchema != nil {
+		params.Arguments = applyArgumentDefaults(toolToUse.ToolInputSchema, params.Arguments)
+	} else if toolToUse.DefaultsSchema != nil {
+		params.Arguments = applyArgumentDefaults(toolToUse.DefaultsSchema, params.Arguments)
 	}
-	return toolToUse.Handler(ctx, params), nil
+	if toolToUse.ToolInputSchema != nil {
+		arguments, err := s.argumentsForValidation(params.Arguments)
+		if err != nil {
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to decode arguments"))
+		}
+		if validationErr := validateArguments(toolToUse.ToolInputSchema, arguments); validationErr != nil {
+			return nil, protocol.NewRPCError(protocol.ErrorCodeInvalidParams, validationErr.Error(), map[string]string{
+				"pointer": validationErr.Pointer,
+			})
+		}
+	}
+	handler := func(ctx context.Context, params baseCallToolRequestParams) *toolResponseSent {
+		return s.toolCache.call(params.Name, params.Arguments, func() *toolResponseSent {
+			return toolToUse.Handler(ctx, params)
+		})
+	}
+	if len(s.toolMiddleware) == 0 && len(toolToUse.Middleware) == 0 {
+		response := handler(ctx, params)
+		var argsErr *argumentsError
+		if response != nil && errors.As(response.Error, &argsErr) {
+			return nil, argsErr.rpcError()
+		}
+		return response, nil
+	}
+
+	// Global middleware is the outermost, then the tool's own middleware
+	middleware := make([]ToolMiddleware, 0, len(s.toolMiddleware)+len(toolToUse.Middleware))
+	middleware = append(middleware, s.toolMiddleware...)
+	middleware = append(middleware, toolToUse.Middleware...)
+	response, err := chainToolMiddleware(toolHandlerFunc(handler), middleware...)(ctx, params.Name, params.Arguments)
+	if response == nil && err == nil {
+		err = errors.New("tool middleware returned no response")
+	}
+	if response != nil && response.IsError {
+		return newToolResponseSent(response), nil
+	}
+	var argsErr *argumentsError
+	if errors.As(err, &argsErr) {
+		return nil, argsErr.rpcError()
+	}
+	if err != nil {
+		return newToolResponseSentError(err), nil
+	}
+	return newToolResponseSent(response), nil
+}
+
+// newInvalidParamsError reports err to the client with the JSON-RPC invalid params code
+func newInvalidParamsError(err error) error {
+	return protocol.NewRPCError(protocol.ErrorCodeInvalidParams, err.Error(), nil)
+}
+
+// argumentsError is the error of a tool call whose arguments don't decode into the handler's arguments.
+// It is sent as an invalid params error rather than as a tool error, as the client sent bad input.
+type argumentsError struct {
+	err error
+}
+
+func (e *argumentsError) Error() string {
+	return "failed to unmarshal arguments: " + e.err.Error()
+}
+
+func (e *argumentsError) Unwrap() error {
+	return e.err
+}
+
+// rpcError returns the invalid params error sent to the client, with the decode error in its data
+func (e *argumentsError) rpcError() error {
+	data := map[string]string{"detail": e.err.Error()}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(e.err, &typeErr) && typeErr.Field != "" {
+		data["field"] = typeErr.Field
+	}
+	return protocol.NewRPCError(protocol.ErrorCodeInvalidParams, e.Error(), data)
 }
+
+// generateCapabilities advertises tools, prompts and resources only if the server has any when the client initializes,
+// or a fallback or lister that provides them. Changes to advertised kinds are sent as list_changed notifications,
+// but a kind that is first registered after the client initialized stays unadvertised for that client.
 func (s *Server) generateCapabilities() ServerCapabilities {
-	t := false
-	return ServerCapabilities{
-		Tools: func() *ServerCapabilitiesTools {
-			return &ServerCapabilitiesTools{
-				ListChanged: &t,
-			}
-		}(),
-		Prompts: func() *ServerCapabilitiesPrompts {
-			return &ServerCapabilitiesPrompts{
-				ListChanged: &t,
-			}
-		}(),
-		Resources: func() *ServerCapabilitiesResources {
-			return &ServerCapabilitiesResources{
-				ListChanged: &t,
-			}
-		}(),
+	// The client is notified when prompts and resources change while serving, and tools unless they are static
+	t := true
+	toolsListChanged := !s.staticTools
+	capabilities := ServerCapabilities{}
+	if !s.tools.Empty() || s.toolFallback.Load() != nil {
+		capabilities.Tools = &ServerCapabilitiesTools{
+			ListChanged: &toolsListChanged,
+		}
+	}
+	if !s.prompts.Empty() {
+		capabilities.Prompts = &ServerCapabilitiesPrompts{
+			ListChanged: &t,
+		}
 	}
+	if !s.resources.Empty() || !s.resourceTemplates.Empty() || s.resourceLister.Load() != nil {
+		capabilities.Resources = &ServerCapabilitiesResources{
+			ListChanged: &t,
+		}
+	}
+	return capabilities
 }
+
 func (s *Server) handleListPrompts(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
 	type promptRequestParams struct {
 		Cursor *string `json:"cursor"`
@@ -743,7 +1625,7 @@ func (s *Server) handleListPrompts(ctx context.Context, request *transport.BaseJ
 	if request.Params != nil {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
 		}
 	}
 
@@ -762,7 +1644,7 @@ func (s *Server) handleListPrompts(ctx context.Context, request *transport.BaseJ
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to decode cursor"))
 		}
 		cString := string(c)
 		// Iterate through the prompts until we find an entry > the cursor
@@ -798,24 +1680,37 @@ func (s *Server) handleListPrompts(ctx context.Context, request *transport.BaseJ
 			}
 			return nil
 		}(),
+		Total: s.paginationTotal(len(orderedPrompts)),
 	}, nil
 }
 
 func (s *Server) handleListResources(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
 	type resourceRequestParams struct {
 		Cursor *string `json:"cursor"`
+		Scheme *string `json:"scheme"`
+		Prefix *string `json:"prefix"`
 	}
 	var params resourceRequestParams
 	if request.Params != nil {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
 		}
 	}
 
-	// Order by URI for pagination
+	if lister := s.resourceLister.Load(); lister != nil {
+		return listDynamicResources(ctx, *lister, params.Cursor, params.Scheme, params.Prefix)
+	}
+
+	// Order by URI for pagination, filtering first so that pages are taken from the filtered set
 	var orderedResources []*resource
 	s.resources.Range(func(k string, r *resource) bool {
+		if params.Scheme != nil && !hasScheme(r.Uri, *params.Scheme) {
+			return true
+		}
+		if params.Prefix != nil && !strings.HasPrefix(r.Uri, *params.Prefix) {
+			return true
+		}
 		orderedResources = append(orderedResources, r)
 		return true
 	})
@@ -828,7 +1723,7 @@ func (s *Server) handleListResources(ctx context.Context, request *transport.Bas
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to decode cursor"))
 		}
 		cString := string(c)
 		// Iterate through the resources until we find an entry > the cursor
@@ -868,6 +1763,40 @@ func (s *Server) handleListResources(ctx context.Context, request *transport.Bas
 			}
 			return nil
 		}(),
+		Total: s.paginationTotal(len(orderedResources)),
+	}, nil
+}
+
+// hasScheme reports whether uri has the given scheme, which like all URI schemes is case-insensitive
+func hasScheme(uri string, scheme string) bool {
+	uriScheme, _, found := strings.Cut(uri, ":")
+	return found && strings.EqualFold(uriScheme, scheme)
+}
+
+// listDynamicResources lists resources with the user provided lister. Filters are applied to the page it returns.
+func listDynamicResources(ctx context.Context, lister resourceLister, cursor *string, scheme *string, prefix *string) (transport.JsonRpcBody, error) {
+	response, err := lister(ctx, cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list resources")
+	}
+	if response == nil {
+		return ListResourcesResponse{Resources: make([]*ResourceSchema, 0)}, nil
+	}
+
+	resourcesToReturn := make([]*ResourceSchema, 0, len(response.Resources))
+	for _, r := range response.Resources {
+		if scheme != nil && !hasScheme(r.Uri, *scheme) {
+			continue
+		}
+		if prefix != nil && !strings.HasPrefix(r.Uri, *prefix) {
+			continue
+		}
+		resourcesToReturn = append(resourcesToReturn, r)
+	}
+	return ListResourcesResponse{
+		Resources:  resourcesToReturn,
+		NextCursor: response.NextCursor,
+		Total:      response.Total,
 	}, nil
 }
 
@@ -879,7 +1808,7 @@ func (s *Server) handleListResourceTemplates(ctx context.Context, request *trans
 	if request.Params != nil {
 		err := json.Unmarshal(request.Params, &params)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal arguments")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
 		}
 	}
 
@@ -898,7 +1827,7 @@ func (s *Server) handleListResourceTemplates(ctx context.Context, request *trans
 		// Base64 decode the cursor
 		c, err := base64.StdEncoding.DecodeString(*params.Cursor)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode cursor")
+			return nil, newInvalidParamsError(errors.Wrap(err, "failed to decode cursor"))
 		}
 		cString := string(c)
 		// Iterate through the templates until we find an entry > the cursor
@@ -946,7 +1875,7 @@ func (s *Server) handlePromptCalls(ctx context.Context, req *transport.BaseJSONR
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
 	}
 
 	var promptToUse *prompt
@@ -959,7 +1888,7 @@ func (s *Server) handlePromptCalls(ctx context.Context, req *transport.BaseJSONR
 	})
 
 	if promptToUse == nil {
-		return nil, errors.Wrapf(err, "unknown prompt: %s", req.Method)
+		return nil, newInvalidParamsError(fmt.Errorf("unknown prompt: %s", params.Name))
 	}
 	return promptToUse.Handler(ctx, params), nil
 }
@@ -969,24 +1898,138 @@ func (s *Server) handleResourceCalls(ctx context.Context, req *transport.BaseJSO
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+		return nil, newInvalidParamsError(errors.Wrap(err, "failed to unmarshal arguments"))
 	}
 
-	var resourceToUse *resource
-	s.resources.Range(func(k string, r *resource) bool {
-		if k != params.Uri {
-			return true
+	// An exact match wins, so resources registered with a query in their URI keep working
+	resourceToUse, ok := s.resources.Load(params.Uri)
+	if !ok {
+		if path, rawQuery, hasQuery := strings.Cut(params.Uri, "?"); hasQuery {
+			rawQuery, _, _ = strings.Cut(rawQuery, "#")
+			query, err := url.ParseQuery(rawQuery)
+			if err != nil {
+				return nil, newInvalidParamsError(errors.Wrap(err, "failed to parse resource uri query"))
+			}
+			if r, ok := s.resources.Load(path); ok {
+				resourceToUse = r
+				ctx = context.WithValue(ctx, resourceQueryKey{}, query)
+			}
 		}
-		resourceToUse = r
-		return false
-	})
+	}
 
 	if resourceToUse == nil {
-		return nil, errors.Wrapf(err, "unknown prompt: %s", req.Method)
+		return nil, protocol.NewRPCError(protocol.ErrorCodeResourceNotFound, fmt.Sprintf("unknown resource: %s", params.Uri), map[string]string{"uri": params.Uri})
 	}
-	return resourceToUse.Handler(ctx), nil
+	if params.Range != nil {
+		if params.Range.Start < 0 || params.Range.End <= params.Range.Start {
+			return nil, newInvalidParamsError(fmt.Errorf("invalid range: start %d, end %d", params.Range.Start, params.Range.End))
+		}
+		ctx = context.WithValue(ctx, resourceRangeKey{}, params.Range)
+	}
+	var response *resourceResponseSent
+	if s.resourceReads != nil {
+		response = s.resourceReads.read(ctx, resourceReadKey(params.Uri, params.Range), resourceToUse.Handler)
+	} else {
+		response = resourceToUse.Handler(ctx)
+	}
+	if response.Error == nil && response.Response != nil && params.IfNoneMatch != nil && response.Response.ETag != nil && *response.Response.ETag == *params.IfNoneMatch {
+		return newResourceResponseSent(&ResourceResponse{
+			Contents:     make([]*EmbeddedResource, 0),
+			Size:         response.Response.Size,
+			ETag:         response.Response.ETag,
+			LastModified: response.Response.LastModified,
+			NotModified:  true,
+		}), nil
+	}
+	return response, nil
+}
+
+func (s *Server) handleDescribe(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	methods := make([]MethodDescription, 0)
+	builtin := map[string]bool{}
+	pr := s.session(ctx).protocol
+	for _, method := range pr.RequestMethods() {
+		builtin[method] = true
+		methods = append(methods, MethodDescription{Name: method, Type: MethodTypeRequest})
+	}
+	s.methods.Range(func(key, value any) bool {
+		if method := key.(string); !builtin[method] {
+			methods = append(methods, MethodDescription{Name: method, Type: MethodTypeRequest})
+		}
+		return true
+	})
+	for _, method := range pr.NotificationMethods() {
+		methods = append(methods, MethodDescription{Name: method, Type: MethodTypeNotification})
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Name == methods[j].Name {
+			return methods[i].Type < methods[j].Type
+		}
+		return methods[i].Name < methods[j].Name
+	})
+	return DescribeResponse{Methods: methods}, nil
 }
 
+// Describe returns every registered tool, prompt and resource.
+// Registrations are blocked while the snapshot is taken, so it never reflects a partially applied change.
+func (s *Server) Describe() ServerDescription {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	description := ServerDescription{
+		Tools:     make([]ToolRetType, 0),
+		Prompts:   make([]*PromptSchema, 0),
+		Resources: make([]*ResourceSchema, 0),
+	}
+	s.tools.Range(func(name string, t *tool) bool {
+		var inputSchema interface{} = t.ToolInputSchema
+		if t.RawInputSchema != nil {
+			inputSchema = t.RawInputSchema
+		}
+		toolDescription := t.Description
+		description.Tools = append(description.Tools, ToolRetType{
+			Name:        t.Name,
+			Description: &toolDescription,
+			InputSchema: inputSchema,
+			Meta:        t.Meta,
+		})
+		return true
+	})
+	s.prompts.Range(func(name string, p *prompt) bool {
+		promptDescription := p.Description
+		description.Prompts = append(description.Prompts, &PromptSchema{
+			Arguments:   p.PromptInputSchema.Arguments,
+			Description: &promptDescription,
+			Name:        p.Name,
+		})
+		return true
+	})
+	s.resources.Range(func(uri string, r *resource) bool {
+		resourceDescription := r.Description
+		mimeType := r.mimeType
+		description.Resources = append(description.Resources, &ResourceSchema{
+			Description: &resourceDescription,
+			MimeType:    &mimeType,
+			Name:        r.Name,
+			Uri:         r.Uri,
+		})
+		return true
+	})
+
+	sort.Slice(description.Tools, func(i, j int) bool {
+		return description.Tools[i].Name < description.Tools[j].Name
+	})
+	sort.Slice(description.Prompts, func(i, j int) bool {
+		return description.Prompts[i].Name < description.Prompts[j].Name
+	})
+	sort.Slice(description.Resources, func(i, j int) bool {
+		return description.Resources[i].Uri < description.Resources[j].Uri
+	})
+	return description
+}
+
+// handlePing answers ping with an empty result. It doesn't depend on the server having been initialized,
+// as the spec allows ping before initialize.
 func (s *Server) handlePing(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
 	return map[string]interface{}{}, nil
 }
@@ -1036,10 +2079,19 @@ var (
 		FieldNameTag:               "",
 		IgnoredTypes:               nil,
 		Lookup:                     nil,
-		Mapper:                     nil,
+		Mapper:                     mapJsonSchemaType,
 		Namer:                      nil,
 		KeyNamer:                   nil,
 		AdditionalFields:           nil,
 		CommentMap:                 nil,
 	}
 )
+
+// mapJsonSchemaType overrides the schema of types that the reflector would otherwise describe by their Go kind
+func mapJsonSchemaType(t reflect.Type) *jsonschema.Schema {
+	// A json.Number is a string in Go, but a number in JSON
+	if t == reflect.TypeOf(json.Number("")) {
+		return &jsonschema.Schema{Type: "number"}
+	}
+	return nil
+}
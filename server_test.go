@@ -2,7 +2,15 @@ package mcp_golang
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/metoro-io/mcp-golang/internal/protocol"
 	"github.com/metoro-io/mcp-golang/internal/testingutils"
@@ -83,6 +91,9 @@ func TestServerListChangedNotifications(t *testing.T) {
 	if messages[0].JsonRpcNotification.Method != "notifications/prompts/list_changed" {
 		t.Errorf("Expected prompts list changed notification, got %s", messages[0].JsonRpcNotification.Method)
 	}
+	if listChanged := server.generateCapabilities().Prompts.ListChanged; listChanged == nil || !*listChanged {
+		t.Error("Expected prompts listChanged to be advertised, as prompt changes are notified")
+	}
 
 	// Test prompt deregistration notification
 	mockTransport = testingutils.NewMockTransport()
@@ -129,6 +140,9 @@ func TestServerListChangedNotifications(t *testing.T) {
 	if messages[0].JsonRpcNotification.Method != "notifications/resources/list_changed" {
 		t.Errorf("Expected resources list changed notification, got %s", messages[0].JsonRpcNotification.Method)
 	}
+	if listChanged := server.generateCapabilities().Resources.ListChanged; listChanged == nil || !*listChanged {
+		t.Error("Expected resources listChanged to be advertised, as resource changes are notified")
+	}
 
 	// Test resource deregistration notification
 	mockTransport = testingutils.NewMockTransport()
@@ -573,6 +587,215 @@ func TestHandleListResourcesPagination(t *testing.T) {
 	}
 }
 
+func TestListTotal(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport(), WithPaginationLimit(2))
+	type args struct{}
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		if err := server.RegisterTool(name, "Test tool", func(arguments args) (*ToolResponse, error) {
+			return NewToolResponse(), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := server.RegisterPrompt(name, "Test prompt", func(arguments args) (*PromptResponse, error) {
+			return NewPromptResponse("", NewPromptMessage(NewTextContent(name), RoleUser)), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		uri := "file:///" + name
+		if err := server.RegisterResource(uri, name, "Test resource", "text/plain", func() (*ResourceResponse, error) {
+			return NewResourceResponse(NewTextEmbeddedResource(uri, "", "text/plain")), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every page reports the total across all pages
+	totals := func(cursors map[string]*string) map[string]*int {
+		params := func(method string) json.RawMessage {
+			if cursors[method] == nil {
+				return json.RawMessage(`{}`)
+			}
+			return json.RawMessage(`{"cursor":"` + *cursors[method] + `"}`)
+		}
+		result := make(map[string]*int)
+		tools, err := server.handleListTools(context.Background(), &transport.BaseJSONRPCRequest{Params: params("tools")}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result["tools"], cursors["tools"] = tools.(ToolsResponse).Total, tools.(ToolsResponse).NextCursor
+		prompts, err := server.handleListPrompts(context.Background(), &transport.BaseJSONRPCRequest{Params: params("prompts")}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result["prompts"], cursors["prompts"] = prompts.(ListPromptsResponse).Total, prompts.(ListPromptsResponse).NextCursor
+		resources, err := server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{Params: params("resources")}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result["resources"], cursors["resources"] = resources.(ListResourcesResponse).Total, resources.(ListResourcesResponse).NextCursor
+		return result
+	}
+	cursors := make(map[string]*string)
+	for page := 1; page <= 3; page++ {
+		for method, total := range totals(cursors) {
+			if total == nil || *total != 5 {
+				t.Errorf("Expected a total of 5 %s on page %d, got %v", method, page, total)
+			}
+		}
+	}
+
+	// The total is left out when the server doesn't paginate
+	server.paginationLimit = nil
+	for method, total := range totals(make(map[string]*string)) {
+		if total != nil {
+			t.Errorf("Expected no total of %s without pagination, got %d", method, *total)
+		}
+	}
+
+	marshalled, err := json.Marshal(ToolsResponse{Tools: []ToolRetType{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(marshalled) != `{"tools":[]}` {
+		t.Errorf("Expected total to be omitted, got %s", marshalled)
+	}
+}
+
+func TestWithInstructionsFunc(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport(), WithInstructions("Use the weather tool."), WithInstructionsFunc(func(ctx context.Context) string {
+		header, ok := HTTPHeaderFromContext(ctx)
+		if !ok {
+			return ""
+		}
+		switch header.Get("Accept-Language") {
+		case "fr":
+			return "Utilisez l'outil météo."
+		case "de":
+			return "Verwenden Sie das Wetter-Tool."
+		}
+		return ""
+	}))
+
+	initialize := func(acceptLanguage string) string {
+		ctx := context.Background()
+		if acceptLanguage != "" {
+			ctx = transport.ContextWithHTTPHeader(ctx, http.Header{"Accept-Language": []string{acceptLanguage}})
+		}
+		result, err := server.handleInitialize(ctx, &transport.BaseJSONRPCRequest{Params: json.RawMessage(`{}`)}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *result.(InitializeResponse).Instructions
+	}
+	if instructions := initialize("fr"); instructions != "Utilisez l'outil météo." {
+		t.Errorf("Unexpected French instructions %q", instructions)
+	}
+	if instructions := initialize("de"); instructions != "Verwenden Sie das Wetter-Tool." {
+		t.Errorf("Unexpected German instructions %q", instructions)
+	}
+	// The static instructions are sent when there are no localized ones
+	if instructions := initialize("es"); instructions != "Use the weather tool." {
+		t.Errorf("Expected the static instructions for an unknown locale, got %q", instructions)
+	}
+	if instructions := initialize(""); instructions != "Use the weather tool." {
+		t.Errorf("Expected the static instructions without headers, got %q", instructions)
+	}
+}
+
+func TestHandleListResourcesFilter(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register resources with mixed schemes
+	resourceURIs := []string{"file:///b.txt", "http://example.com/a", "file:///a.txt", "file:///docs/c.txt", "db://users"}
+	for _, uri := range resourceURIs {
+		err = server.RegisterResource(uri, "resource-"+uri, "Test resource "+uri, "text/plain", func() (*ResourceResponse, error) {
+			return NewResourceResponse(NewTextEmbeddedResource(uri, "test content", "text/plain")), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Filter by scheme
+	resp, err := server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"scheme":"file"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourcesResp, ok := resp.(ListResourcesResponse)
+	if !ok {
+		t.Fatal("Expected ListResourcesResponse")
+	}
+	if len(resourcesResp.Resources) != 3 {
+		t.Fatalf("Expected 3 file resources, got %d", len(resourcesResp.Resources))
+	}
+	for _, r := range resourcesResp.Resources {
+		if r.Uri[:7] != "file://" {
+			t.Errorf("Unexpected resource in filtered list: %s", r.Uri)
+		}
+	}
+
+	// Schemes are case-insensitive
+	resp, err = server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"scheme":"FILE"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(resp.(ListResourcesResponse).Resources); n != 3 {
+		t.Fatalf("Expected 3 file resources for scheme FILE, got %d", n)
+	}
+
+	// Filter by prefix
+	resp, err = server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"prefix":"file:///docs/"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourcesResp = resp.(ListResourcesResponse)
+	if len(resourcesResp.Resources) != 1 || resourcesResp.Resources[0].Uri != "file:///docs/c.txt" {
+		t.Errorf("Unexpected resources for prefix filter: %v", resourcesResp.Resources)
+	}
+
+	// Pagination applies to the filtered set
+	limit := 2
+	server.paginationLimit = &limit
+	resp, err = server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"scheme":"file"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourcesResp = resp.(ListResourcesResponse)
+	if len(resourcesResp.Resources) != 2 {
+		t.Fatalf("Expected 2 resources in first page, got %d", len(resourcesResp.Resources))
+	}
+	if resourcesResp.Resources[0].Uri != "file:///a.txt" || resourcesResp.Resources[1].Uri != "file:///b.txt" {
+		t.Errorf("Unexpected resources in first page: %v", resourcesResp.Resources)
+	}
+	if resourcesResp.NextCursor == nil {
+		t.Fatal("Expected next cursor for first page")
+	}
+
+	resp, err = server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"scheme":"file","cursor":"` + *resourcesResp.NextCursor + `"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourcesResp = resp.(ListResourcesResponse)
+	if len(resourcesResp.Resources) != 1 || resourcesResp.Resources[0].Uri != "file:///docs/c.txt" {
+		t.Errorf("Unexpected resources in second page: %v", resourcesResp.Resources)
+	}
+}
+
 func TestHandleListResourceTemplatesPagination(t *testing.T) {
 	mockTransport := testingutils.NewMockTransport()
 	server := NewServer(mockTransport)
@@ -706,3 +929,1201 @@ func TestHandleListResourceTemplatesPagination(t *testing.T) {
 		t.Error("Expected no next cursor when pagination is disabled")
 	}
 }
+
+func TestRegisterToolFromSchema(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := json.RawMessage(`{"type":"object","properties":{"city":{"type":"string","description":"The city"}},"required":["city"]}`)
+	var receivedArgs json.RawMessage
+	err = server.RegisterToolFromSchema("weather", "Get the weather", schema, func(args json.RawMessage) (*ToolResponse, error) {
+		receivedArgs = args
+		return NewToolResponse(NewTextContent("sunny")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The schema is listed as provided
+	resp, err := server.handleListTools(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolsResp := resp.(ToolsResponse)
+	if len(toolsResp.Tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(toolsResp.Tools))
+	}
+	listedSchema, err := json.Marshal(toolsResp.Tools[0].InputSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(listedSchema) != string(schema) {
+		t.Errorf("Expected schema %s, got %s", schema, listedSchema)
+	}
+
+	// The handler receives the raw arguments
+	resp, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":{"city":"London"}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(receivedArgs) != `{"city":"London"}` {
+		t.Errorf("Unexpected arguments passed to handler: %s", receivedArgs)
+	}
+	result, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `{"content":[{"text":"sunny","type":"text"}],"isError":false}` {
+		t.Errorf("Unexpected tool result: %s", result)
+	}
+
+	// A status set on the response is reported to the transport but not sent in the result
+	err = server.RegisterToolFromSchema("lookup", "Look up a record", schema, func(args json.RawMessage) (*ToolResponse, error) {
+		return NewToolResponseWithStatus(404, NewTextContent("not found")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"lookup","arguments":{"city":"London"}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statusCoder, ok := resp.(transport.StatusCoder)
+	if !ok || statusCoder.StatusCode() != 404 {
+		t.Errorf("Expected the tool result to report status 404, got %v", resp)
+	}
+	result, err = json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `{"content":[{"text":"not found","type":"text"}],"isError":false}` {
+		t.Errorf("Unexpected tool result: %s", result)
+	}
+
+	// Invalid schemas are rejected
+	err = server.RegisterToolFromSchema("invalid", "Invalid schema", json.RawMessage(`[1,2]`), func(args json.RawMessage) (*ToolResponse, error) {
+		return NewToolResponse(), nil
+	})
+	if err == nil {
+		t.Error("Expected error when registering a tool with a non-object schema")
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	type args struct {
+		City string `json:"city" jsonschema:"required"`
+	}
+	type forecast struct {
+		City        string  `json:"city"`
+		Temperature float64 `json:"temperature"`
+	}
+
+	server := NewServer(testingutils.NewMockTransport())
+	supported := map[string]any{
+		"tool_response": func(a args) (*ToolResponse, error) {
+			return NewToolResponse(NewTextContent("sunny in " + a.City)), nil
+		},
+		"string": func(ctx context.Context, a args) (string, error) {
+			return "sunny in " + a.City, nil
+		},
+		"struct": func(a args) (forecast, error) {
+			return forecast{City: a.City, Temperature: 21.5}, nil
+		},
+	}
+	expected := map[string]string{
+		"tool_response": `{"content":[{"text":"sunny in London","type":"text"}],"isError":false}`,
+		"string":        `{"content":[{"text":"sunny in London","type":"text"}],"isError":false}`,
+		"struct":        `{"content":[{"text":"{\"city\":\"London\",\"temperature\":21.5}","type":"text"}],"isError":false}`,
+	}
+	for name, fn := range supported {
+		if err := server.RegisterFunc(name, "Get the weather", fn); err != nil {
+			t.Fatalf("Expected %s to be supported, got %v", name, err)
+		}
+		resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(`{"name":"` + name + `","arguments":{"city":"London"}}`),
+		}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != expected[name] {
+			t.Errorf("Unexpected result for %s: %s", name, result)
+		}
+	}
+
+	// Errors from the function are sent as tool errors
+	if err := server.RegisterFunc("failing", "Always fails", func(a args) (forecast, error) {
+		return forecast{}, errors.New("service unavailable")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"failing","arguments":{"city":"London"}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := resp.(*toolResponseSent); sent.Error == nil {
+		t.Error("Expected the function's error to be sent as a tool error")
+	}
+
+	unsupported := map[string]any{
+		"not a function":         "weather",
+		"no arguments":           func() (string, error) { return "", nil },
+		"non struct argument":    func(city string) (string, error) { return city, nil },
+		"context not first":      func(a args, ctx context.Context) (string, error) { return "", nil },
+		"missing error":          func(a args) string { return "" },
+		"error not last":         func(a args) (error, string) { return nil, "" },
+		"too many return values": func(a args) (string, string, error) { return "", "", nil },
+	}
+	for name, fn := range unsupported {
+		if err := server.RegisterFunc("unsupported", "Unsupported", fn); err == nil {
+			t.Errorf("Expected an error when registering a function with %s", name)
+		}
+	}
+	if server.CheckToolRegistered("unsupported") {
+		t.Error("Unsupported functions must not be registered")
+	}
+}
+
+func TestRegisterDynamicTool(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := json.RawMessage(`{"type":"object","properties":{"plugin":{"type":"string"}},"required":["plugin"],"additionalProperties":true}`)
+	var receivedArgs map[string]any
+	err = server.RegisterDynamicTool("plugin", "Run a plugin", schema, func(args map[string]any) (*ToolResponse, error) {
+		receivedArgs = args
+		return NewToolResponse(NewTextContent("ok")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys that are not in the schema reach the handler
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"plugin","arguments":{"plugin":"resize","width":640,"options":{"crop":true}}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receivedArgs["plugin"] != "resize" || receivedArgs["width"] != float64(640) {
+		t.Errorf("Unexpected arguments passed to handler: %v", receivedArgs)
+	}
+	if options, ok := receivedArgs["options"].(map[string]any); !ok || options["crop"] != true {
+		t.Errorf("Expected nested arguments to be decoded as a map, got %v", receivedArgs["options"])
+	}
+
+	// Arguments are validated against the schema
+	receivedArgs = nil
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"plugin","arguments":{"width":640}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err == nil {
+		t.Error("Expected an error when a required argument is missing")
+	}
+	if receivedArgs != nil {
+		t.Error("Handler must not be called with invalid arguments")
+	}
+
+	// The schema is listed as provided
+	resp, err := server.handleListTools(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listedSchema, err := json.Marshal(resp.(ToolsResponse).Tools[0].InputSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(listedSchema) != string(schema) {
+		t.Errorf("Expected schema %s, got %s", schema, listedSchema)
+	}
+}
+
+func TestDescribeEndpoint(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport, WithDescribeEndpoint())
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.handleDescribe(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	describeResp, ok := resp.(DescribeResponse)
+	if !ok {
+		t.Fatal("Expected DescribeResponse")
+	}
+
+	methods := map[string]MethodType{}
+	for _, m := range describeResp.Methods {
+		methods[m.Name] = m.Type
+	}
+	expected := map[string]MethodType{
+		"initialize":                MethodTypeRequest,
+		"ping":                      MethodTypeRequest,
+		"tools/list":                MethodTypeRequest,
+		"tools/call":                MethodTypeRequest,
+		"prompts/list":              MethodTypeRequest,
+		"prompts/get":               MethodTypeRequest,
+		"resources/list":            MethodTypeRequest,
+		"resources/templates/list":  MethodTypeRequest,
+		"resources/read":            MethodTypeRequest,
+		"server/describe":           MethodTypeRequest,
+		"notifications/initialized": MethodTypeNotification,
+		"notifications/cancelled":   MethodTypeNotification,
+	}
+	for name, methodType := range expected {
+		if methods[name] != methodType {
+			t.Errorf("Expected method %s of type %q, got %q", name, methodType, methods[name])
+		}
+	}
+
+	// The endpoint is disabled by default
+	server = NewServer(testingutils.NewMockTransport())
+	err = server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range server.protocol.RequestMethods() {
+		if method == "server/describe" {
+			t.Error("Expected server/describe to be disabled by default")
+		}
+	}
+}
+
+func TestServerDescribe(t *testing.T) {
+	type weatherArgs struct {
+		City string `json:"city" jsonschema:"required,description=The city to get the weather for"`
+	}
+	type greetingArgs struct {
+		Name string `json:"name" jsonschema:"required,description=Who to greet"`
+	}
+	server := NewServer(testingutils.NewMockTransport())
+	err := server.RegisterTool("weather", "Get the weather", func(args weatherArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent("sunny")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterTool("echo", "Echo the input", func(args weatherArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(args.City)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterPrompt("greeting", "Greet someone", func(args greetingArgs) (*PromptResponse, error) {
+		return NewPromptResponse("greeting", NewPromptMessage(NewTextContent("hello "+args.Name), RoleUser)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterResource("file:///readme.md", "readme", "The readme", "text/markdown", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///readme.md", "# readme", "text/markdown")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	description := server.Describe()
+	if len(description.Tools) != 2 || description.Tools[0].Name != "echo" || description.Tools[1].Name != "weather" {
+		t.Fatalf("Expected tools echo and weather in order, got %+v", description.Tools)
+	}
+	if *description.Tools[1].Description != "Get the weather" {
+		t.Errorf("Expected tool description %q, got %q", "Get the weather", *description.Tools[1].Description)
+	}
+	if len(description.Prompts) != 1 || description.Prompts[0].Name != "greeting" {
+		t.Fatalf("Expected prompt greeting, got %+v", description.Prompts)
+	}
+	if len(description.Prompts[0].Arguments) != 1 || description.Prompts[0].Arguments[0].Name != "Name" {
+		t.Errorf("Expected prompt argument Name, got %+v", description.Prompts[0].Arguments)
+	}
+	if len(description.Resources) != 1 || description.Resources[0].Uri != "file:///readme.md" || *description.Resources[0].MimeType != "text/markdown" {
+		t.Fatalf("Expected resource file:///readme.md, got %+v", description.Resources)
+	}
+
+	// The description serializes to JSON, including the tools' input schemas
+	marshalled, err := json.Marshal(description)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(marshalled, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.Tools[1].InputSchema.Properties["city"]; !ok {
+		t.Errorf("Expected the weather input schema to have a city property, got %s", marshalled)
+	}
+
+	// Deregistrations are reflected in the next snapshot
+	if err := server.DeregisterTool("echo"); err != nil {
+		t.Fatal(err)
+	}
+	if tools := server.Describe().Tools; len(tools) != 1 || tools[0].Name != "weather" {
+		t.Errorf("Expected only the weather tool after deregistering echo, got %+v", tools)
+	}
+}
+
+func TestServeMultipleTransports(t *testing.T) {
+	firstServerTransport, firstClientTransport := newPipeTransports(t)
+	secondServerTransport, secondClientTransport := newPipeTransports(t)
+
+	server := NewServer(firstServerTransport)
+	err := server.AddTransport(secondServerTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type echoArgs struct {
+		Message string `json:"message"`
+	}
+	calls := 0
+	err = server.RegisterTool("echo", "Echo the message", func(args echoArgs) (*ToolResponse, error) {
+		calls++
+		return NewToolResponse(NewTextContent(args.Message)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.AddTransport(testingutils.NewMockTransport()); err == nil {
+		t.Error("Expected error when adding a transport to a running server")
+	}
+
+	for i, clientTransport := range []transport.Transport{firstClientTransport, secondClientTransport} {
+		client := NewClient(clientTransport)
+		_, err := client.Initialize(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to initialize client %d: %v", i, err)
+		}
+		response, err := client.CallTool(context.Background(), "echo", echoArgs{Message: "hello"})
+		if err != nil {
+			t.Fatalf("Failed to call tool over transport %d: %v", i, err)
+		}
+		if len(response.Content) != 1 || response.Content[0].TextContent.Text != "hello" {
+			t.Errorf("Unexpected response over transport %d: %v", i, response.Content)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected the tool to be called twice, got %d", calls)
+	}
+}
+
+func TestServeMultipleTransportsSessions(t *testing.T) {
+	firstServerTransport, firstClientTransport := newPipeTransports(t)
+	secondServerTransport, secondClientTransport := newPipeTransports(t)
+
+	server := NewServer(firstServerTransport, WithDescribeEndpoint())
+	if err := server.AddTransport(secondServerTransport); err != nil {
+		t.Fatal(err)
+	}
+	type askArgs struct{}
+	err := server.RegisterTool("ask", "Ask the user", func(ctx context.Context, args askArgs) (*ToolResponse, error) {
+		result, err := server.Elicit(ctx, json.RawMessage(`{"type":"object"}`), "Continue?")
+		if err != nil {
+			return nil, err
+		}
+		return NewToolResponse(NewTextContent(string(result))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the client of the first transport supports elicitation, the second one initializes last
+	firstClient := NewClient(firstClientTransport)
+	firstClient.SetElicitationHandler(func(ctx context.Context, request ElicitationRequest) (*ElicitationResponse, error) {
+		return &ElicitationResponse{Action: ElicitationActionAccept}, nil
+	})
+	if _, err := firstClient.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	secondClient := NewClient(secondClientTransport)
+	if _, err := secondClient.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := firstClient.CallTool(context.Background(), "ask", askArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.IsError || !strings.Contains(response.Content[0].TextContent.Text, "accept") {
+		t.Errorf("Expected the first client to answer the elicitation, got %+v", response.Content[0].TextContent)
+	}
+	response, err = secondClient.CallTool(context.Background(), "ask", askArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.IsError || !strings.Contains(response.Content[0].TextContent.Text, ErrElicitationNotSupported.Error()) {
+		t.Errorf("Expected elicitation to be unsupported for the second client, got %+v", response.Content[0].TextContent)
+	}
+
+	// Each session describes the methods of its own protocol
+	description, err := secondClient.Describe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(description.Methods) == 0 {
+		t.Error("Expected the second session to describe its methods")
+	}
+}
+
+// failingTransport is a transport that fails to start
+type failingTransport struct {
+	*testingutils.MockTransport
+}
+
+func (t failingTransport) Start(ctx context.Context) error {
+	return errors.New("address already in use")
+}
+
+func TestServeClosesTransportsOnFailure(t *testing.T) {
+	running := testingutils.NewMockTransport()
+	server := NewServer(running)
+	if err := server.AddTransport(failingTransport{testingutils.NewMockTransport()}); err != nil {
+		t.Fatal(err)
+	}
+	err := server.Serve()
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("Expected the failing transport's error, got %v", err)
+	}
+	if !running.IsClosed() {
+		t.Error("Expected the transport that started to be closed")
+	}
+}
+
+func TestSetResourceLister(t *testing.T) {
+	type userKey struct{}
+
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterResource("file:///static.txt", "static", "A static resource", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///static.txt", "static", "text/plain")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var receivedCursor *string
+	server.SetResourceLister(func(ctx context.Context, cursor *string) (*ListResourcesResponse, error) {
+		receivedCursor = cursor
+		user, _ := ctx.Value(userKey{}).(string)
+		next := "page-2"
+		return &ListResourcesResponse{
+			Resources: []*ResourceSchema{
+				{Name: "orders", Uri: "db://" + user + "/orders"},
+			},
+			NextCursor: &next,
+		}, nil
+	})
+
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	resp, err := server.handleListResources(ctx, &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"cursor":"page-1"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourcesResp := resp.(ListResourcesResponse)
+	if len(resourcesResp.Resources) != 1 || resourcesResp.Resources[0].Uri != "db://alice/orders" {
+		t.Errorf("Expected the dynamic resources for alice, got %v", resourcesResp.Resources)
+	}
+	if receivedCursor == nil || *receivedCursor != "page-1" {
+		t.Errorf("Expected the cursor to be passed to the lister, got %v", receivedCursor)
+	}
+	if resourcesResp.NextCursor == nil || *resourcesResp.NextCursor != "page-2" {
+		t.Errorf("Expected the lister's next cursor, got %v", resourcesResp.NextCursor)
+	}
+
+	// Removing the lister falls back to the static registry
+	server.SetResourceLister(nil)
+	resp, err = server.handleListResources(ctx, &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourcesResp = resp.(ListResourcesResponse)
+	if len(resourcesResp.Resources) != 1 || resourcesResp.Resources[0].Uri != "file:///static.txt" {
+		t.Errorf("Expected the static resources, got %v", resourcesResp.Resources)
+	}
+}
+
+func TestSetResourceListerWhileServing(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		server.SetResourceLister(func(ctx context.Context, cursor *string) (*ListResourcesResponse, error) {
+			return &ListResourcesResponse{Resources: []*ResourceSchema{{Uri: "db://row/1", Name: "row"}}}, nil
+		})
+		server.SetResourceLister(nil)
+	}
+	wg.Wait()
+}
+
+func TestHandleResourceCallsWithQuery(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = server.RegisterResource("db://table", "table", "A table", "text/plain", func(ctx context.Context) (*ResourceResponse, error) {
+		limit := ResourceQueryFromContext(ctx).Get("limit")
+		if limit == "" {
+			limit = "all"
+		}
+		return NewResourceResponse(NewTextEmbeddedResource("db://table", "rows: "+limit, "text/plain")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RegisterResource("db://table?view=summary", "summary", "A summary", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("db://table?view=summary", "summary", "text/plain")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(uri string) string {
+		resp, err := server.handleResourceCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(`{"uri":"` + uri + `"}`),
+		}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", uri, err)
+		}
+		return resp.(*resourceResponseSent).Response.Contents[0].TextResourceContents.Text
+	}
+
+	if got := read("db://table"); got != "rows: all" {
+		t.Errorf("Expected the handler to see no query, got %q", got)
+	}
+	if got := read("db://table?limit=10"); got != "rows: 10" {
+		t.Errorf("Expected the handler to see the query, got %q", got)
+	}
+	// A resource registered with a query is still matched exactly
+	if got := read("db://table?view=summary"); got != "summary" {
+		t.Errorf("Expected the exact match, got %q", got)
+	}
+
+	_, err = server.handleResourceCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"uri":"db://other?limit=10"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err == nil {
+		t.Error("Expected an error for an unknown resource with a query")
+	}
+}
+
+func TestToolFallback(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	err := server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = server.RegisterToolFromSchema("local", "A local tool", json.RawMessage(`{"type":"object"}`), func(args json.RawMessage) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent("local")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fallbackName string
+	var fallbackArgs json.RawMessage
+	server.SetToolFallback(func(ctx context.Context, name string, args json.RawMessage) (*ToolResponse, error) {
+		fallbackName = name
+		fallbackArgs = args
+		return NewToolResponse(NewTextContent("forwarded " + name)), nil
+	})
+
+	call := func(params string) *toolResponseSent {
+		resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(params),
+		}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.(*toolResponseSent)
+	}
+
+	resp := call(`{"name":"remote_search","arguments":{"query":"mcp"}}`)
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if fallbackName != "remote_search" || string(fallbackArgs) != `{"query":"mcp"}` {
+		t.Errorf("Expected the fallback to receive the call, got %s %s", fallbackName, fallbackArgs)
+	}
+	if resp.Response.Content[0].TextContent.Text != "forwarded remote_search" {
+		t.Errorf("Unexpected fallback response %q", resp.Response.Content[0].TextContent.Text)
+	}
+
+	// Registered tools are not routed to the fallback
+	fallbackName = ""
+	resp = call(`{"name":"local","arguments":{}}`)
+	if fallbackName != "" || resp.Response.Content[0].TextContent.Text != "local" {
+		t.Error("Expected the registered tool to handle the call")
+	}
+
+	// Only registered tools are listed
+	listResp, err := server.handleListTools(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tools := listResp.(ToolsResponse).Tools; len(tools) != 1 || tools[0].Name != "local" {
+		t.Errorf("Expected only the local tool to be listed, got %v", tools)
+	}
+}
+
+func TestSetToolFallbackWhileServing(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_, _ = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+				Params: []byte(`{"name":"remote","arguments":{}}`),
+			}, protocol.RequestHandlerExtra{})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		server.SetToolFallback(func(ctx context.Context, name string, args json.RawMessage) (*ToolResponse, error) {
+			return NewToolResponse(NewTextContent("forwarded")), nil
+		})
+		server.SetToolFallback(nil)
+	}
+	wg.Wait()
+
+	// Removing the fallback makes unknown tools fail again
+	_, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"remote","arguments":{}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err == nil {
+		t.Error("Expected an error for an unknown tool without a fallback")
+	}
+}
+
+func TestPingBeforeAndAfterInitialize(t *testing.T) {
+	serverTransport, clientTransport := newPipeTransports(t)
+	server := NewServer(serverTransport)
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	clientProtocol := protocol.NewProtocol(nil)
+	if err := clientProtocol.Connect(clientTransport); err != nil {
+		t.Fatal(err)
+	}
+
+	ping := func() {
+		var result json.RawMessage
+		if err := clientProtocol.RequestInto(context.Background(), "ping", nil, &result, nil); err != nil {
+			t.Fatalf("Ping failed: %v", err)
+		}
+		if string(result) != `{}` {
+			t.Errorf("Expected an empty result, got %s", result)
+		}
+	}
+
+	ping()
+	err := clientProtocol.RequestInto(context.Background(), "initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      ClientInfo{Name: "test", Version: "1.0.0"},
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ping()
+}
+
+func TestNullRequestParams(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	type args struct {
+		Message string `json:"message"`
+	}
+	if err := server.RegisterTool("echo", "Echo a message", func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Transports may hand over null params as is, or drop them
+	requests := []*transport.BaseJSONRPCRequest{
+		{Jsonrpc: "2.0", Id: 1, Method: "tools/list", Params: json.RawMessage("null")},
+		{Jsonrpc: "2.0", Id: 2, Method: "tools/list"},
+		{Jsonrpc: "2.0", Id: 3, Method: "tools/call"},
+	}
+	for i, request := range requests {
+		mockTransport.SimulateMessage(transport.NewBaseMessageRequest(request))
+		deadline := time.Now().Add(time.Second)
+		for len(mockTransport.GetMessages()) <= i && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if len(mockTransport.GetMessages()) <= i {
+			t.Fatalf("No response to request %d", request.Id)
+		}
+	}
+
+	messages := mockTransport.GetMessages()
+	for _, message := range messages[:2] {
+		if message.Type != transport.BaseMessageTypeJSONRPCResponseType {
+			t.Fatalf("Expected tools/list to succeed, got %+v", message.JsonRpcError)
+		}
+		var tools ToolsResponse
+		if err := json.Unmarshal(message.JsonRpcResponse.Result, &tools); err != nil {
+			t.Fatal(err)
+		}
+		if len(tools.Tools) != 1 {
+			t.Errorf("Expected 1 tool, got %d", len(tools.Tools))
+		}
+	}
+	// Absent params are read as an empty object, so the missing tool name is reported rather than a decoding error
+	if messages[2].Type != transport.BaseMessageTypeJSONRPCErrorType {
+		t.Fatalf("Expected tools/call without a name to fail")
+	}
+	if message := messages[2].JsonRpcError.Error.Message; message != "unknown tool: " {
+		t.Errorf("Expected an unknown tool error, got %q", message)
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	type args struct {
+		Message string `json:"message"`
+	}
+	ids := make(chan transport.RequestId, 1)
+	if err := server.RegisterTool("echo", "Echo a message", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		id, ok := RequestIDFromContext(ctx)
+		if !ok {
+			return nil, errors.New("no request id in context")
+		}
+		ids <- id
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	mockTransport.SimulateMessage(transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      77,
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"echo","arguments":{"message":"hi"}}`),
+	}))
+	select {
+	case id := <-ids:
+		if id != 77 {
+			t.Errorf("Expected request id 77, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Tool was not called")
+	}
+
+	if _, ok := RequestIDFromContext(context.Background()); ok {
+		t.Error("Expected no request id outside of a handler")
+	}
+}
+
+func TestClaimsFromContext(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	type args struct{}
+	if err := server.RegisterTool("whoami", "Return the caller", func(ctx context.Context, arguments args) (*ToolResponse, error) {
+		claims, ok := ClaimsFromContext(ctx)
+		if !ok {
+			return nil, errors.New("no claims in context")
+		}
+		return NewToolResponse(NewTextContent(claims["sub"].(string))), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := transport.ContextWithClaims(context.Background(), transport.Claims{"sub": "user-1"})
+	result, err := server.handleToolCalls(ctx, &transport.BaseJSONRPCRequest{
+		Params: json.RawMessage(`{"name":"whoami","arguments":{}}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := result.(*toolResponseSent)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if text := response.Response.Content[0].TextContent.Text; text != "user-1" {
+		t.Errorf("Expected the caller user-1, got %q", text)
+	}
+
+	if _, ok := ClaimsFromContext(context.Background()); ok {
+		t.Error("Expected no claims outside of an authorized request")
+	}
+}
+
+func TestRegisterPromptRaw(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	schema := json.RawMessage(`{"type":"object","properties":{"template":{"type":"string","description":"The template to render"},"vars":{"type":"object"}},"required":["template"]}`)
+	var receivedArgs json.RawMessage
+	err := server.RegisterPromptRaw("render", "Render a template", schema, func(args json.RawMessage) (*PromptResponse, error) {
+		receivedArgs = args
+		return NewPromptResponse("render", NewPromptMessage(NewTextContent("rendered"), RoleUser)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Arguments are passed through as sent, including ones that are not strings or not in the schema
+	arguments := `{"template":"Hello {{name}}","vars":{"name":"Ada","count":2},"extra":[1,2]}`
+	resp, err := server.handlePromptCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"render","arguments":` + arguments + `}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := resp.(*promptResponseSent); sent.Error != nil {
+		t.Fatalf("Expected no error, got %v", sent.Error)
+	}
+	if string(receivedArgs) != arguments {
+		t.Errorf("Expected arguments %s, got %s", arguments, receivedArgs)
+	}
+
+	// The schema's properties are listed as the prompt's arguments
+	listResp, err := server.handleListPrompts(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts := listResp.(ListPromptsResponse).Prompts
+	if len(prompts) != 1 || len(prompts[0].Arguments) != 2 {
+		t.Fatalf("Expected 1 prompt with 2 arguments, got %+v", prompts)
+	}
+	template, vars := prompts[0].Arguments[0], prompts[0].Arguments[1]
+	if template.Name != "template" || !*template.Required || template.Description == nil || *template.Description != "The template to render" {
+		t.Errorf("Unexpected template argument %+v", template)
+	}
+	if vars.Name != "vars" || *vars.Required || vars.Description != nil {
+		t.Errorf("Unexpected vars argument %+v", vars)
+	}
+
+	if err := server.RegisterPromptRaw("invalid", "Invalid schema", json.RawMessage(`[]`), func(args json.RawMessage) (*PromptResponse, error) {
+		return nil, nil
+	}); err == nil {
+		t.Error("Expected an error for a schema that is not an object")
+	}
+}
+
+func TestWithStaticTools(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	echo := func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}
+
+	// Tools can change by default, which is advertised to the client
+	server := NewServer(testingutils.NewMockTransport())
+	if err := server.RegisterTool("echo", "Echo a message", echo); err != nil {
+		t.Fatal(err)
+	}
+	if listChanged := server.generateCapabilities().Tools.ListChanged; listChanged == nil || !*listChanged {
+		t.Error("Expected tools listChanged to be advertised by default")
+	}
+
+	server = NewServer(testingutils.NewMockTransport(), WithStaticTools())
+	if err := server.RegisterTool("echo", "Echo a message", echo); err != nil {
+		t.Fatalf("Expected tools to be registered before Serve, got %v", err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	if listChanged := server.generateCapabilities().Tools.ListChanged; listChanged == nil || *listChanged {
+		t.Error("Expected tools listChanged to be false for static tools")
+	}
+
+	// The registry is locked once serving
+	if err := server.RegisterTool("echo2", "Echo a message", echo); !errors.Is(err, ErrStaticTools) {
+		t.Errorf("Expected ErrStaticTools registering a tool, got %v", err)
+	}
+	if err := server.RegisterToolFromSchema("raw", "Raw tool", json.RawMessage(`{"type":"object"}`), func(args json.RawMessage) (*ToolResponse, error) {
+		return NewToolResponse(), nil
+	}); !errors.Is(err, ErrStaticTools) {
+		t.Errorf("Expected ErrStaticTools registering a tool from a schema, got %v", err)
+	}
+	if err := server.DeregisterTool("echo"); !errors.Is(err, ErrStaticTools) {
+		t.Errorf("Expected ErrStaticTools deregistering a tool, got %v", err)
+	}
+	if !server.CheckToolRegistered("echo") || server.CheckToolRegistered("echo2") {
+		t.Error("Expected the tools to be unchanged")
+	}
+}
+
+type schemaCacheArgs struct {
+	City  string   `json:"city" jsonschema:"required,description=The city to get the weather for"`
+	Days  int      `json:"days" jsonschema:"minimum=1,maximum=14"`
+	Units string   `json:"units" jsonschema:"enum=metric,enum=imperial"`
+	Tags  []string `json:"tags"`
+}
+
+func TestRegisterToolReusesSchema(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	handler := func(arguments schemaCacheArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.City)), nil
+	}
+	for _, name := range []string{"weather", "forecast"} {
+		if err := server.RegisterTool(name, "Get the weather", handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	weather, _ := server.tools.Load("weather")
+	forecast, _ := server.tools.Load("forecast")
+	if weather.ToolInputSchema != forecast.ToolInputSchema {
+		t.Error("Expected tools with the same argument type to share their schema")
+	}
+	if weather.ToolInputSchema.Properties.Len() != 4 {
+		t.Errorf("Expected 4 properties, got %d", weather.ToolInputSchema.Properties.Len())
+	}
+}
+
+func BenchmarkRegisterTool(b *testing.B) {
+	handler := func(arguments schemaCacheArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.City)), nil
+	}
+	b.Run("uncached", func(b *testing.B) {
+		argumentType := reflect.TypeOf(schemaCacheArgs{})
+		for i := 0; i < b.N; i++ {
+			jsonSchemaReflector.ReflectFromType(argumentType)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		server := NewServer(testingutils.NewMockTransport())
+		for i := 0; i < b.N; i++ {
+			if err := server.RegisterTool(fmt.Sprintf("tool-%d", i), "Get the weather", handler); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkToolCall measures the per-call overhead of a server with a single tool
+func BenchmarkToolCall(b *testing.B) {
+	handler := func(arguments schemaCacheArgs) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.City)), nil
+	}
+	request := &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":{"city":"Paris"}}`),
+	}
+	server := NewServer(testingutils.NewMockTransport())
+	if err := server.RegisterTool("weather", "Get the weather", handler); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := server.handleToolCalls(context.Background(), request, protocol.RequestHandlerExtra{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRegisterResourceValidatesUri(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	handler := func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///a.txt", "a", "text/plain")), nil
+	}
+
+	for _, uri := range []string{"", "a.txt", "/tmp/a.txt", "%zz://a"} {
+		if err := server.RegisterResource(uri, "invalid", "Invalid resource", "text/plain", handler); err == nil {
+			t.Errorf("Expected an error registering resource %q", uri)
+		}
+		if server.CheckResourceRegistered(uri) {
+			t.Errorf("Expected resource %q not to be registered", uri)
+		}
+	}
+
+	if err := server.RegisterResource("file:///a.txt", "a", "Resource a", "text/plain", handler); err != nil {
+		t.Fatal(err)
+	}
+	err := server.RegisterResource("file:///a.txt", "a2", "Duplicate of a", "text/plain", handler)
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("Expected an already registered error, got %v", err)
+	}
+
+	// A deregistered uri can be registered again
+	if err := server.DeregisterResource("file:///a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterResource("file:///a.txt", "a", "Resource a", "text/plain", handler); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDuplicateRegistration(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+	toolHandler := func(arguments args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(arguments.Message)), nil
+	}
+	promptHandler := func(arguments args) (*PromptResponse, error) {
+		return NewPromptResponse("greeting", NewPromptMessage(NewTextContent(arguments.Message), RoleUser)), nil
+	}
+	resourceHandler := func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///a.txt", "a", "text/plain")), nil
+	}
+	register := func(server *Server) (toolErr, promptErr, resourceErr error) {
+		return server.RegisterTool("echo", "Echo a message", toolHandler),
+			server.RegisterPrompt("greeting", "A greeting", promptHandler),
+			server.RegisterResource("file:///a.txt", "a", "Resource a", "text/plain", resourceHandler)
+	}
+
+	server := NewServer(testingutils.NewMockTransport())
+	toolErr, promptErr, resourceErr := register(server)
+	if toolErr != nil || promptErr != nil || resourceErr != nil {
+		t.Fatalf("Expected the first registrations to succeed, got %v, %v, %v", toolErr, promptErr, resourceErr)
+	}
+	toolErr, promptErr, resourceErr = register(server)
+	if !errors.Is(toolErr, ErrToolAlreadyRegistered) {
+		t.Errorf("Expected ErrToolAlreadyRegistered, got %v", toolErr)
+	}
+	if !errors.Is(promptErr, ErrPromptAlreadyRegistered) {
+		t.Errorf("Expected ErrPromptAlreadyRegistered, got %v", promptErr)
+	}
+	if !errors.Is(resourceErr, ErrResourceAlreadyRegistered) {
+		t.Errorf("Expected ErrResourceAlreadyRegistered, got %v", resourceErr)
+	}
+	err := server.RegisterToolFromSchema("echo", "Raw echo", json.RawMessage(`{"type":"object"}`), func(args json.RawMessage) (*ToolResponse, error) {
+		return NewToolResponse(), nil
+	})
+	if !errors.Is(err, ErrToolAlreadyRegistered) {
+		t.Errorf("Expected ErrToolAlreadyRegistered registering a tool from a schema, got %v", err)
+	}
+
+	// Deregistering frees the name
+	if err := server.DeregisterTool("echo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterTool("echo", "Echo a message", toolHandler); err != nil {
+		t.Errorf("Expected a deregistered tool to be registered again, got %v", err)
+	}
+
+	server = NewServer(testingutils.NewMockTransport(), WithOverwrite())
+	register(server)
+	if err := server.RegisterTool("echo", "Echo a message, replaced", toolHandler); err != nil {
+		t.Errorf("Expected the tool to be replaced, got %v", err)
+	}
+	toolErr, promptErr, resourceErr = register(server)
+	if toolErr != nil || promptErr != nil || resourceErr != nil {
+		t.Errorf("Expected registrations to replace existing ones, got %v, %v, %v", toolErr, promptErr, resourceErr)
+	}
+}
+
+func TestReadResourceIfNoneMatchWithoutResponse(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	err := server.RegisterResource("file:///empty.txt", "empty", "A resource without a response", "text/plain", func() (*ResourceResponse, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := server.handleResourceCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"uri":"file:///empty.txt","ifNoneMatch":"v1"}`),
+	}, protocol.RequestHandlerExtra{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, ok := response.(*resourceResponseSent)
+	if !ok || sent.Response != nil {
+		t.Fatalf("Expected the handler's nil response to be passed through, got %#v", response)
+	}
+}
+
+func TestResourceResponseETag(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	err := server.RegisterResource("file:///config.json", "config", "Configuration", "application/json", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("file:///config.json", `{"debug":true}`, "application/json")).WithETag("v1"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(params string) transport.ETagger {
+		response, err := server.handleResourceCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(params),
+		}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		eTagger, ok := response.(transport.ETagger)
+		if !ok {
+			t.Fatalf("Expected the resource result to implement transport.ETagger, got %#v", response)
+		}
+		return eTagger
+	}
+
+	// The handler's etag is reported to the transport
+	if etag, cacheable := read(`{"uri":"file:///config.json"}`).ETag(); !cacheable || etag != "v1" {
+		t.Errorf("Expected the cacheable etag v1, got %q %v", etag, cacheable)
+	}
+	// A NotModified result has no contents to cache
+	if _, cacheable := read(`{"uri":"file:///config.json","ifNoneMatch":"v1"}`).ETag(); cacheable {
+		t.Error("Expected a NotModified result not to be cacheable")
+	}
+	if _, cacheable := newResourceResponseSentError(errors.New("failed")).ETag(); cacheable {
+		t.Error("Expected an error result not to be cacheable")
+	}
+}
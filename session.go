@@ -0,0 +1,33 @@
+package mcp_golang
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// session is the server's state for the clients of one of its transports
+type session struct {
+	protocol *protocol.Protocol
+	// Whether the client advertised the elicitation capability when it initialized
+	clientElicitation atomic.Bool
+}
+
+type sessionKey struct{}
+
+// withSession makes the session of the transport a request came in on available to handler through its context
+func withSession(sess *session, handler requestHandler) requestHandler {
+	return func(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+		return handler(context.WithValue(ctx, sessionKey{}, sess), request, extra)
+	}
+}
+
+// session returns the session of the request in ctx, or the session of the server's main transport outside of requests
+func (s *Server) session(ctx context.Context) *session {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return sess
+	}
+	return s.sessions[0]
+}
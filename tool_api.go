@@ -1,9 +1,28 @@
 package mcp_golang
 
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // This is a union type of all the different ToolResponse that can be sent back to the client.
 // We allow creation through constructors only to make sure that the ToolResponse is valid.
 type ToolResponse struct {
 	Content []*Content `json:"content" yaml:"content" mapstructure:"content"`
+
+	// Whether the tool call ended in an error. The content is still sent to the client so the model can read it.
+	IsError bool `json:"isError,omitempty" yaml:"isError,omitempty" mapstructure:"isError,omitempty"`
+
+	// Status is an optional transport level status, e.g. an HTTP status code. It is not sent as part of the result,
+	// transports that support it (see http.HTTPTransport.WithToolStatusCodes) use it as the status of the response.
+	Status int `json:"-" yaml:"-" mapstructure:"-"`
+
+	// Pre-serialized content blocks, sent instead of Content, see NewRawToolResponse
+	raw json.RawMessage
 }
 
 func NewToolResponse(content ...*Content) *ToolResponse {
@@ -11,3 +30,145 @@ func NewToolResponse(content ...*Content) *ToolResponse {
 		Content: content,
 	}
 }
+
+// NewRawToolResponse creates a ToolResponse whose content is already serialized, e.g. the content of a result
+// forwarded from an upstream server. raw must be a JSON array of content blocks, it is sent as is without being decoded
+// and encoded again. Invalid JSON is only detected when the response is sent, the client then receives an error result.
+func NewRawToolResponse(raw json.RawMessage) *ToolResponse {
+	return &ToolResponse{
+		raw: raw,
+	}
+}
+
+// NewToolResponseWithStatus creates a ToolResponse with a transport level status, e.g. http.StatusNotFound
+func NewToolResponseWithStatus(status int, content ...*Content) *ToolResponse {
+	response := NewToolResponse(content...)
+	response.Status = status
+	return response
+}
+
+// TextContent returns the text of all text content blocks of the response, joined by newlines
+func (r *ToolResponse) TextContent() string {
+	var texts []string
+	for _, content := range r.Content {
+		if content.Type == ContentTypeText && content.TextContent != nil {
+			texts = append(texts, content.TextContent.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// ImageContents returns the decoded data of the image content blocks of the response, in order.
+// Images whose data is not valid base64 are skipped.
+func (r *ToolResponse) ImageContents() [][]byte {
+	var images [][]byte
+	for _, content := range r.Content {
+		if content.Type != ContentTypeImage || content.ImageContent == nil {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(content.ImageContent.Data)
+		if err != nil {
+			continue
+		}
+		images = append(images, data)
+	}
+	return images
+}
+
+// EmbeddedResources returns the resources embedded in the response, in order
+func (r *ToolResponse) EmbeddedResources() []*EmbeddedResource {
+	var resources []*EmbeddedResource
+	for _, content := range r.Content {
+		if content.Type == ContentTypeEmbeddedResource && content.EmbeddedResource != nil {
+			resources = append(resources, content.EmbeddedResource)
+		}
+	}
+	return resources
+}
+
+// NewToolErrorResponse creates a ToolResponse with IsError set and the formatted message as its only text content.
+// Unlike returning an error from a handler, which the client receives as a JSON-RPC error, the message is shown to the model.
+func NewToolErrorResponse(format string, args ...any) *ToolResponse {
+	response := NewToolResponse(NewTextContent(fmt.Sprintf(format, args...)))
+	response.IsError = true
+	return response
+}
+
+// validateRawContent returns an error if raw is not a JSON array
+func validateRawContent(raw json.RawMessage) error {
+	if !json.Valid(raw) {
+		return errors.New("tool returned invalid raw JSON content")
+	}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) == 0 || trimmed[0] != '[' {
+		return errors.New("tool returned raw content that is not a JSON array")
+	}
+	return nil
+}
+
+// ToolResponseBuilder builds a ToolResponse that mixes several kinds of content.
+// Example:
+//
+//	response, err := NewToolResponseBuilder().
+//		AddText("Here is the chart you asked for").
+//		AddImage(base64PNG, "image/png").
+//		Build()
+type ToolResponseBuilder struct {
+	content []*Content
+	isError bool
+}
+
+// NewToolResponseBuilder creates an empty ToolResponseBuilder
+func NewToolResponseBuilder() *ToolResponseBuilder {
+	return &ToolResponseBuilder{}
+}
+
+// AddText appends a text content block
+func (b *ToolResponseBuilder) AddText(text string) *ToolResponseBuilder {
+	b.content = append(b.content, NewTextContent(text))
+	return b
+}
+
+// AddImage appends an image content block. The given data must already be base64-encoded
+func (b *ToolResponseBuilder) AddImage(base64EncodedStringData string, mimeType string) *ToolResponseBuilder {
+	b.content = append(b.content, NewImageContent(base64EncodedStringData, mimeType))
+	return b
+}
+
+// AddResource appends an embedded resource content block
+func (b *ToolResponseBuilder) AddResource(resource *EmbeddedResource) *ToolResponseBuilder {
+	b.content = append(b.content, &Content{
+		Type:             ContentTypeEmbeddedResource,
+		EmbeddedResource: resource,
+	})
+	return b
+}
+
+// AddContent appends an already constructed content block
+func (b *ToolResponseBuilder) AddContent(content *Content) *ToolResponseBuilder {
+	b.content = append(b.content, content)
+	return b
+}
+
+// AsError marks the response as an error response
+func (b *ToolResponseBuilder) AsError() *ToolResponseBuilder {
+	b.isError = true
+	return b
+}
+
+// Build returns the ToolResponse. A response that is not marked as an error must contain at least one content block
+func (b *ToolResponseBuilder) Build() (*ToolResponse, error) {
+	if len(b.content) == 0 && !b.isError {
+		return nil, errors.New("tool response must contain at least one content block")
+	}
+	response := NewToolResponse(b.content...)
+	response.IsError = b.isError
+	return response, nil
+}
+
+// WithToolMeta attaches metadata to a tool, e.g. a category, icon or tags, which is listed under the tool's _meta field in tools/list.
+// The map is sent as is, so clients receive any fields they don't know about unchanged.
+func WithToolMeta(meta map[string]any) ToolOption {
+	return func(t *tool) {
+		t.Meta = meta
+	}
+}
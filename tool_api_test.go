@@ -0,0 +1,125 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolResponseBuilder(t *testing.T) {
+	t.Run("mixed content", func(t *testing.T) {
+		response, err := NewToolResponseBuilder().
+			AddText("Here is the file").
+			AddImage("aGVsbG8=", "image/png").
+			AddResource(NewTextEmbeddedResource("file:///a.txt", "contents", "text/plain")).
+			Build()
+		require.NoError(t, err)
+		require.Len(t, response.Content, 3)
+		assert.False(t, response.IsError)
+
+		b, err := json.Marshal(newToolResponseSent(response))
+		require.NoError(t, err)
+
+		var decoded struct {
+			Content []map[string]interface{} `json:"content"`
+			IsError bool                     `json:"isError"`
+		}
+		require.NoError(t, json.Unmarshal(b, &decoded))
+		require.Len(t, decoded.Content, 3)
+		assert.Equal(t, "text", decoded.Content[0]["type"])
+		assert.Equal(t, "Here is the file", decoded.Content[0]["text"])
+		assert.Equal(t, "image", decoded.Content[1]["type"])
+		assert.Equal(t, "image/png", decoded.Content[1]["mimeType"])
+		assert.Equal(t, "resource", decoded.Content[2]["type"])
+		assert.Equal(t, "file:///a.txt", decoded.Content[2]["uri"])
+		assert.False(t, decoded.IsError)
+	})
+
+	t.Run("empty response is rejected", func(t *testing.T) {
+		_, err := NewToolResponseBuilder().Build()
+		assert.Error(t, err)
+	})
+
+	t.Run("empty error response is allowed", func(t *testing.T) {
+		response, err := NewToolResponseBuilder().AsError().Build()
+		require.NoError(t, err)
+		assert.True(t, response.IsError)
+	})
+}
+
+func TestNewToolErrorResponse(t *testing.T) {
+	response := NewToolErrorResponse("city %q not found", "Atlantis")
+	assert.True(t, response.IsError)
+	require.Len(t, response.Content, 1)
+	assert.Equal(t, ContentTypeText, response.Content[0].Type)
+	assert.Equal(t, `city "Atlantis" not found`, response.Content[0].TextContent.Text)
+
+	marshalled, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"content":[{"type":"text","text":"city \"Atlantis\" not found"}],"isError":true}`, string(marshalled))
+}
+
+func TestToolResponseContentAccessors(t *testing.T) {
+	// A mixed content response, as received by a client
+	sent := NewToolResponse(
+		NewTextContent("First paragraph"),
+		NewImageContent("aGVsbG8=", "image/png"),
+		NewTextResourceContent("file:///a.txt", "contents", "text/plain"),
+		NewTextContent("Second paragraph"),
+		NewImageContent("not base64!", "image/png"),
+		NewImageContent("d29ybGQ=", "image/jpeg"),
+	)
+	marshalled, err := json.Marshal(sent)
+	require.NoError(t, err)
+	var response ToolResponse
+	require.NoError(t, json.Unmarshal(marshalled, &response))
+
+	assert.Equal(t, "First paragraph\nSecond paragraph", response.TextContent())
+	assert.Equal(t, [][]byte{[]byte("hello"), []byte("world")}, response.ImageContents())
+	resources := response.EmbeddedResources()
+	require.Len(t, resources, 1)
+	require.NotNil(t, resources[0].TextResourceContents)
+	assert.Equal(t, "file:///a.txt", resources[0].TextResourceContents.Uri)
+	assert.Equal(t, "contents", resources[0].TextResourceContents.Text)
+
+	// Responses without such content
+	empty := NewToolResponse()
+	assert.Equal(t, "", empty.TextContent())
+	assert.Empty(t, empty.ImageContents())
+	assert.Empty(t, empty.EmbeddedResources())
+}
+
+func TestNewRawToolResponse(t *testing.T) {
+	type proxyArgs struct {
+		Query string `json:"query"`
+	}
+	upstream := `[{"type":"text","text":"{\"rows\":[1,2]}"}]`
+	server := NewServer(nil)
+	err := server.RegisterTool("proxy", "Forwards upstream results", func(arguments proxyArgs) (*ToolResponse, error) {
+		if arguments.Query == "broken" {
+			return NewRawToolResponse(json.RawMessage(`[{"type":"text"`)), nil
+		}
+		return NewRawToolResponse(json.RawMessage(upstream)), nil
+	})
+	require.NoError(t, err)
+
+	call := func(query string) []byte {
+		result, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(`{"name":"proxy","arguments":{"query":"` + query + `"}}`),
+		}, protocol.RequestHandlerExtra{})
+		require.NoError(t, err)
+		marshalled, err := json.Marshal(result)
+		require.NoError(t, err)
+		return marshalled
+	}
+
+	// The content is sent as is, not as a JSON string
+	assert.Equal(t, `{"content":`+upstream+`,"isError":false}`, string(call("rows")))
+
+	assert.JSONEq(t, `{"content":[{"type":"text","text":"tool returned invalid raw JSON content"}],"isError":true}`, string(call("broken")))
+}
@@ -0,0 +1,155 @@
+package mcp_golang
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// toolCacheSize is the maximum number of responses a toolCache holds across all tools
+const toolCacheSize = 1024
+
+// toolCache is a least recently used cache of the responses of tools that have caching enabled, keyed by tool name and arguments
+type toolCache struct {
+	mu      sync.Mutex
+	size    int
+	ttls    map[string]time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type toolCacheEntry struct {
+	key      string
+	response *toolResponseSent
+	expires  time.Time
+}
+
+func newToolCache() *toolCache {
+	return &toolCache{
+		size:    toolCacheSize,
+		ttls:    make(map[string]time.Duration),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// enable turns on caching for the tool. A ttl of zero or less disables it.
+func (c *toolCache) enable(toolName string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl <= 0 {
+		delete(c.ttls, toolName)
+	} else {
+		c.ttls[toolName] = ttl
+	}
+	c.invalidateLocked(toolName)
+}
+
+// invalidate drops all cached responses of the tool
+func (c *toolCache) invalidate(toolName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(toolName)
+}
+
+func (c *toolCache) invalidateLocked(toolName string) {
+	prefix := toolName + "\x00"
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.removeLocked(element)
+		}
+	}
+}
+
+func (c *toolCache) removeLocked(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*toolCacheEntry).key)
+}
+
+// storeLocked caches the response, dropping expired responses and then the least recently used ones while the cache is full
+func (c *toolCache) storeLocked(key string, response *toolResponseSent, now time.Time, ttl time.Duration) {
+	if element, ok := c.entries[key]; ok {
+		c.removeLocked(element)
+	}
+	for _, element := range c.entries {
+		if !now.Before(element.Value.(*toolCacheEntry).expires) {
+			c.removeLocked(element)
+		}
+	}
+	c.entries[key] = c.order.PushFront(&toolCacheEntry{key: key, response: response, expires: now.Add(ttl)})
+	for c.order.Len() > c.size {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// call returns the cached response for the arguments if there is one, and otherwise calls the handler.
+// Successful responses are cached, errors never are.
+func (c *toolCache) call(toolName string, arguments json.RawMessage, handler func() *toolResponseSent) *toolResponseSent {
+	c.mu.Lock()
+	ttl, ok := c.ttls[toolName]
+	c.mu.Unlock()
+	if !ok {
+		return handler()
+	}
+
+	key, err := toolCacheKey(toolName, arguments)
+	if err != nil {
+		// Arguments that can't be canonicalized are passed through uncached, the handler reports the error
+		return handler()
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*toolCacheEntry)
+		if now.Before(entry.expires) {
+			c.order.MoveToFront(element)
+			c.mu.Unlock()
+			return entry.response
+		}
+		c.removeLocked(element)
+	}
+	c.mu.Unlock()
+
+	response := handler()
+	if response == nil || response.Error != nil || response.Response == nil || response.Response.IsError {
+		return response
+	}
+
+	c.mu.Lock()
+	c.storeLocked(key, response, time.Now(), ttl)
+	c.mu.Unlock()
+	return response
+}
+
+// toolCacheKey hashes the canonical form of the arguments, so that calls that only differ in field order share a key
+func toolCacheKey(toolName string, arguments json.RawMessage) (string, error) {
+	canonical, err := canonicalizeJSON(arguments)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return toolName + "\x00" + hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalizeJSON re-encodes the JSON value with object keys sorted and insignificant whitespace removed.
+// Missing or null arguments are treated as an empty object.
+func canonicalizeJSON(data json.RawMessage) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return []byte("{}"), nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written so that large integers don't lose precision
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	// encoding/json sorts map keys when marshaling
+	return json.Marshal(value)
+}
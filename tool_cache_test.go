@@ -0,0 +1,122 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+func TestToolCache(t *testing.T) {
+	type args struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+
+	server := NewServer(testingutils.NewMockTransport(), WithToolCache("add", time.Minute))
+	calls := 0
+	err := server.RegisterTool("add", "Add two numbers", func(arguments args) (*ToolResponse, error) {
+		calls++
+		return NewToolResponse(NewTextContent(fmt.Sprint(arguments.A + arguments.B))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(params string) *toolResponseSent {
+		resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(params),
+		}, protocol.RequestHandlerExtra{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.(*toolResponseSent)
+	}
+
+	// Field order does not change the cache key
+	first := call(`{"name":"add","arguments":{"a":1,"b":2}}`)
+	second := call(`{"name":"add","arguments":{ "b": 2, "a": 1 }}`)
+	if calls != 1 {
+		t.Errorf("Expected the handler to run once for identical calls, ran %d times", calls)
+	}
+	if second.Response.Content[0].TextContent.Text != first.Response.Content[0].TextContent.Text {
+		t.Errorf("Expected the cached response to match, got %q", second.Response.Content[0].TextContent.Text)
+	}
+
+	call(`{"name":"add","arguments":{"a":2,"b":2}}`)
+	if calls != 2 {
+		t.Errorf("Expected different arguments to call the handler, ran %d times", calls)
+	}
+
+	// A non-cacheable tool always runs
+	server.DisableToolCache("add")
+	call(`{"name":"add","arguments":{"a":1,"b":2}}`)
+	if calls != 3 {
+		t.Errorf("Expected the handler to run after disabling the cache, ran %d times", calls)
+	}
+}
+
+func TestCanonicalizeJSON(t *testing.T) {
+	a, err := canonicalizeJSON(json.RawMessage(`{"b":{"y":1,"x":[2,1]},"a":12345678901234567890}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := canonicalizeJSON(json.RawMessage(`{ "a": 12345678901234567890, "b": {"x": [2, 1], "y": 1} }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("Expected equal canonical forms, got %s and %s", a, b)
+	}
+	if string(a) != `{"a":12345678901234567890,"b":{"x":[2,1],"y":1}}` {
+		t.Errorf("Unexpected canonical form %s", a)
+	}
+}
+
+func TestToolCacheEviction(t *testing.T) {
+	cache := newToolCache()
+	cache.enable("short", time.Millisecond)
+	cache.enable("long", time.Minute)
+	respond := func() *toolResponseSent {
+		return newToolResponseSent(NewToolResponse(NewTextContent("ok")))
+	}
+
+	// Expired responses are removed when a new one is stored, even if their arguments are never used again
+	for i := 0; i < 10; i++ {
+		cache.call("short", json.RawMessage(fmt.Sprintf(`{"i":%d}`, i)), respond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	cache.call("long", json.RawMessage(`{"i":0}`), respond)
+	if len(cache.entries) != 1 || cache.order.Len() != 1 {
+		t.Fatalf("Expected expired responses to be removed, %d entries left", len(cache.entries))
+	}
+
+	// The least recently used response is evicted once the cache is full
+	cache.size = 2
+	cache.call("long", json.RawMessage(`{"i":1}`), respond)
+	cache.call("long", json.RawMessage(`{"i":0}`), respond)
+	cache.call("long", json.RawMessage(`{"i":2}`), respond)
+	if len(cache.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(cache.entries))
+	}
+	calls := 0
+	cache.call("long", json.RawMessage(`{"i":0}`), func() *toolResponseSent {
+		calls++
+		return respond()
+	})
+	if calls != 0 {
+		t.Error("Expected the recently used response to stay cached")
+	}
+	cache.call("long", json.RawMessage(`{"i":1}`), func() *toolResponseSent {
+		calls++
+		return respond()
+	})
+	if calls != 1 {
+		t.Error("Expected the least recently used response to be evicted")
+	}
+}
@@ -0,0 +1,50 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ToolHandlerFunc handles a single tool call with its raw JSON arguments
+type ToolHandlerFunc func(ctx context.Context, name string, arguments json.RawMessage) (*ToolResponse, error)
+
+// ToolMiddleware wraps a tool call, e.g. to add logging, auth checks or argument rewriting.
+// It can run code before and after calling next, or return without calling it.
+type ToolMiddleware func(next ToolHandlerFunc) ToolHandlerFunc
+
+// ToolOption configures a tool at registration
+type ToolOption func(*tool)
+
+// WithToolMiddleware adds middleware that only runs for this tool.
+// It runs inside any middleware added with WithGlobalToolMiddleware, in the order given.
+func WithToolMiddleware(middleware ...ToolMiddleware) ToolOption {
+	return func(t *tool) {
+		t.Middleware = append(t.Middleware, middleware...)
+	}
+}
+
+// WithGlobalToolMiddleware adds middleware that runs for every tool call, in the order given.
+func WithGlobalToolMiddleware(middleware ...ToolMiddleware) ServerOptions {
+	return func(s *Server) {
+		s.toolMiddleware = append(s.toolMiddleware, middleware...)
+	}
+}
+
+// chainToolMiddleware wraps the handler so that the first middleware is the outermost
+func chainToolMiddleware(handler ToolHandlerFunc, middleware ...ToolMiddleware) ToolHandlerFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// toolHandlerFunc adapts a wrapped tool handler to a ToolHandlerFunc so that middleware can call it
+func toolHandlerFunc(handler func(context.Context, baseCallToolRequestParams) *toolResponseSent) ToolHandlerFunc {
+	return func(ctx context.Context, name string, arguments json.RawMessage) (*ToolResponse, error) {
+		response := handler(ctx, baseCallToolRequestParams{Name: name, Arguments: arguments})
+		if response == nil {
+			return nil, nil
+		}
+		return response.Response, response.Error
+	}
+}
@@ -0,0 +1,91 @@
+package mcp_golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/metoro-io/mcp-golang/internal/protocol"
+	"github.com/metoro-io/mcp-golang/internal/testingutils"
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolMiddlewareOrder(t *testing.T) {
+	type args struct {
+		Name string `json:"name"`
+	}
+
+	var calls []string
+	record := func(label string) ToolMiddleware {
+		return func(next ToolHandlerFunc) ToolHandlerFunc {
+			return func(ctx context.Context, name string, arguments json.RawMessage) (*ToolResponse, error) {
+				calls = append(calls, label+" before")
+				response, err := next(ctx, name, arguments)
+				calls = append(calls, label+" after")
+				return response, err
+			}
+		}
+	}
+
+	server := NewServer(testingutils.NewMockTransport(), WithGlobalToolMiddleware(record("global 1"), record("global 2")))
+	err := server.RegisterTool("greet", "Greet someone", func(arguments args) (*ToolResponse, error) {
+		calls = append(calls, "handler")
+		return NewToolResponse(NewTextContent("Hello, " + arguments.Name)), nil
+	}, WithToolMiddleware(record("tool")))
+	require.NoError(t, err)
+	err = server.RegisterTool("plain", "No tool middleware", func(arguments args) (*ToolResponse, error) {
+		calls = append(calls, "handler")
+		return NewToolResponse(NewTextContent("plain")), nil
+	})
+	require.NoError(t, err)
+
+	call := func(params string) *toolResponseSent {
+		resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+			Params: []byte(params),
+		}, protocol.RequestHandlerExtra{})
+		require.NoError(t, err)
+		return resp.(*toolResponseSent)
+	}
+
+	response := call(`{"name":"greet","arguments":{"name":"Ada"}}`)
+	require.NoError(t, response.Error)
+	assert.Equal(t, "Hello, Ada", response.Response.Content[0].TextContent.Text)
+	assert.Equal(t, []string{
+		"global 1 before", "global 2 before", "tool before",
+		"handler",
+		"tool after", "global 2 after", "global 1 after",
+	}, calls)
+
+	// Tool middleware only runs for its own tool
+	calls = nil
+	call(`{"name":"plain","arguments":{}}`)
+	assert.Equal(t, []string{"global 1 before", "global 2 before", "handler", "global 2 after", "global 1 after"}, calls)
+}
+
+func TestToolMiddlewareShortCircuit(t *testing.T) {
+	type args struct{}
+
+	deny := func(next ToolHandlerFunc) ToolHandlerFunc {
+		return func(ctx context.Context, name string, arguments json.RawMessage) (*ToolResponse, error) {
+			return nil, errors.New("permission denied")
+		}
+	}
+
+	handlerCalled := false
+	server := NewServer(testingutils.NewMockTransport())
+	err := server.RegisterTool("delete", "Delete everything", func(arguments args) (*ToolResponse, error) {
+		handlerCalled = true
+		return NewToolResponse(NewTextContent("deleted")), nil
+	}, WithToolMiddleware(deny))
+	require.NoError(t, err)
+
+	resp, err := server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"delete","arguments":{}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.False(t, handlerCalled)
+	assert.EqualError(t, resp.(*toolResponseSent).Error, "permission denied")
+}
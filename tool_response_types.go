@@ -155,8 +155,13 @@ type ToolRetType struct {
 
 	// The name of the tool.
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// Metadata attached to the tool with WithToolMeta.
+	Meta map[string]any `json:"_meta,omitempty" yaml:"_meta,omitempty" mapstructure:"_meta,omitempty"`
 }
 type ToolsResponse struct {
 	Tools      []ToolRetType `json:"tools" yaml:"tools" mapstructure:"tools"`
 	NextCursor *string       `json:"nextCursor,omitempty" yaml:"nextCursor,omitempty" mapstructure:"nextCursor,omitempty"`
+	// Total is the number of tools across all pages. It is only set when the server paginates.
+	Total *int `json:"total,omitempty" yaml:"total,omitempty" mapstructure:"total,omitempty"`
 }
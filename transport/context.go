@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the id the client sent a request with.
+// Transports that rewrite request ids must set the original id before passing the request on,
+// requests without one are given their own id by the protocol.
+func ContextWithRequestID(ctx context.Context, id RequestId) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the id the client sent the request being handled with
+func RequestIDFromContext(ctx context.Context) (RequestId, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(RequestId)
+	return id, ok
+}
+
+// Claims are the claims of a validated access token, e.g. the decoded payload of a JWT
+type Claims map[string]any
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the claims of the token the request was authorized with
+func ContextWithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token the request being handled was authorized with
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+type httpHeaderKey struct{}
+
+// ContextWithHTTPHeader returns a copy of ctx carrying the headers of the HTTP request a message was received in
+func ContextWithHTTPHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, httpHeaderKey{}, header)
+}
+
+// HTTPHeaderFromContext returns the headers of the HTTP request the message being handled was received in
+func HTTPHeaderFromContext(ctx context.Context) (http.Header, bool) {
+	header, ok := ctx.Value(httpHeaderKey{}).(http.Header)
+	return header, ok
+}
+
+type connectionKey struct{}
+
+// ContextWithConnection returns a copy of ctx carrying the context of the connection a message was received over
+func ContextWithConnection(ctx context.Context, connection context.Context) context.Context {
+	return context.WithValue(ctx, connectionKey{}, connection)
+}
+
+// ConnectionFromContext returns the context of the connection the message being handled was received over,
+// see ConnectionTransport
+func ConnectionFromContext(ctx context.Context) (context.Context, bool) {
+	connection, ok := ctx.Value(connectionKey{}).(context.Context)
+	return connection, ok
+}
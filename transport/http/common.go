@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -17,27 +18,78 @@ type baseTransport struct {
 	closeHandler   func()
 	mu             sync.RWMutex
 	responseMap    map[int64]chan *transport.BaseJsonRpcMessage
+	closed         chan struct{}
+	closeOnce      sync.Once
 }
 
+// errTransportClosed is returned for requests that are pending or arrive after the transport was closed
+var errTransportClosed = errors.New("transport is closed")
+
 func newBaseTransport() *baseTransport {
 	return &baseTransport{
 		responseMap: make(map[int64]chan *transport.BaseJsonRpcMessage),
+		closed:      make(chan struct{}),
 	}
 }
 
+// releasePending unblocks all requests that are waiting for a response, and makes any later request fail immediately
+func (t *baseTransport) releasePending() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
+
 // Send implements Transport.Send
 func (t *baseTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
-	key := message.JsonRpcResponse.Id
-	responseChannel := t.responseMap[int64(key)]
+	key, ok := responseKey(message)
+	if !ok {
+		// A stateless HTTP transport has no channel to push messages to the client outside of a response,
+		// so server initiated notifications are dropped
+		return nil
+	}
+	t.mu.RLock()
+	responseChannel := t.responseMap[key]
+	t.mu.RUnlock()
 	if responseChannel == nil {
-		return fmt.Errorf("no response channel found for key: %d", key)
+		return fmt.Errorf("no pending request found for response with id %d", key)
+	}
+	select {
+	case responseChannel <- message:
+		return nil
+	case <-t.closed:
+		return errTransportClosed
+	}
+}
+
+// responseKey returns the key of the pending request that the message answers.
+// It returns false for messages that are not responses to a request, such as notifications.
+func responseKey(message *transport.BaseJsonRpcMessage) (int64, bool) {
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		return int64(message.JsonRpcResponse.Id), true
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		return int64(message.JsonRpcError.Id), true
+	case transport.BaseMessageTypeJSONRPCBatchType:
+		// A batch answers the request of the response it carries, notifications can be sent before or after it
+		for _, m := range message.JsonRpcBatch {
+			if key, ok := responseKey(m); ok {
+				return key, true
+			}
+		}
+		return 0, false
+	default:
+		return 0, false
 	}
-	responseChannel <- message
-	return nil
+}
+
+// BatchesWithResponse implements transport.ResponseBatchTransport, messages can't be sent outside of a response
+func (t *baseTransport) BatchesWithResponse() bool {
+	return true
 }
 
 // Close implements Transport.Close
 func (t *baseTransport) Close() error {
+	t.releasePending()
 	if t.closeHandler != nil {
 		t.closeHandler()
 	}
@@ -67,6 +119,12 @@ func (t *baseTransport) SetMessageHandler(handler func(ctx context.Context, mess
 
 // handleMessage processes an incoming message and returns a response
 func (t *baseTransport) handleMessage(ctx context.Context, body []byte) (*transport.BaseJsonRpcMessage, error) {
+	select {
+	case <-t.closed:
+		return nil, errTransportClosed
+	default:
+	}
+
 	// Store the response writer for later use
 	t.mu.Lock()
 	var key int64 = 0
@@ -94,7 +152,8 @@ func (t *baseTransport) handleMessage(ctx context.Context, body []byte) (*transp
 		t.mu.RUnlock()
 
 		if handler != nil {
-			handler(ctx, transport.NewBaseMessageRequest(&request))
+			// Handlers see the id the client sent rather than the internal key
+			handler(transport.ContextWithRequestID(ctx, id), transport.NewBaseMessageRequest(&request))
 		}
 	}
 
@@ -143,18 +202,45 @@ func (t *baseTransport) handleMessage(ctx context.Context, body []byte) (*transp
 		}
 	}
 
-	// Block until the response is received
-	responseToUse := <-t.responseMap[key]
+	// Block until the response is received or the transport is closed
+	t.mu.RLock()
+	responseChannel := t.responseMap[key]
+	t.mu.RUnlock()
+	var responseToUse *transport.BaseJsonRpcMessage
+	select {
+	case responseToUse = <-responseChannel:
+	case <-t.closed:
+	}
 	t.mu.Lock()
 	delete(t.responseMap, key)
 	t.mu.Unlock()
+	if responseToUse == nil {
+		return nil, errTransportClosed
+	}
 	if prevId != nil {
-		responseToUse.JsonRpcResponse.Id = *prevId
+		setResponseId(responseToUse, *prevId)
 	}
 
 	return responseToUse, nil
 }
 
+// setResponseId sets the id of a response, error or the response a batch carries
+func setResponseId(message *transport.BaseJsonRpcMessage, id transport.RequestId) {
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		message.JsonRpcResponse.Id = id
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		message.JsonRpcError.Id = id
+	case transport.BaseMessageTypeJSONRPCBatchType:
+		for _, m := range message.JsonRpcBatch {
+			if _, ok := responseKey(m); ok {
+				setResponseId(m, id)
+				return
+			}
+		}
+	}
+}
+
 // readBody reads and returns the body from an io.Reader
 func (t *baseTransport) readBody(reader io.Reader) ([]byte, error) {
 	body, err := io.ReadAll(reader)
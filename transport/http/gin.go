@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -10,6 +11,16 @@ import (
 	"github.com/metoro-io/mcp-golang/transport"
 )
 
+// ginContextKey is the context key under which the Gin transport stores the *gin.Context of the current request
+type ginContextKey struct{}
+
+// GinContextFromContext returns the *gin.Context of the request that is being handled by a GinTransport.
+// Handlers can use it to read request data such as headers or the authenticated user.
+func GinContextFromContext(ctx context.Context) (*gin.Context, bool) {
+	c, ok := ctx.Value(ginContextKey{}).(*gin.Context)
+	return c, ok
+}
+
 // GinTransport implements a stateless HTTP transport for MCP using Gin
 type GinTransport struct {
 	*baseTransport
@@ -29,17 +40,13 @@ func (t *GinTransport) Start(ctx context.Context) error {
 
 // Send implements Transport.Send
 func (t *GinTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
-	key := message.JsonRpcResponse.Id
-	responseChannel := t.responseMap[int64(key)]
-	if responseChannel == nil {
-		return fmt.Errorf("no response channel found for key: %d", key)
-	}
-	responseChannel <- message
-	return nil
+	return t.baseTransport.Send(ctx, message)
 }
 
 // Close implements Transport.Close
+// Requests that are still waiting for a response are answered with 503 Service Unavailable.
 func (t *GinTransport) Close() error {
+	t.releasePending()
 	if t.closeHandler != nil {
 		t.closeHandler()
 	}
@@ -71,7 +78,7 @@ func (t *GinTransport) SetMessageHandler(handler func(ctx context.Context, messa
 func (t *GinTransport) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := context.Background()
-		ctx = context.WithValue(ctx, "ginContext", c)
+		ctx = context.WithValue(ctx, ginContextKey{}, c)
 		if c.Request.Method != http.MethodPost {
 			c.String(http.StatusMethodNotAllowed, "Only POST method is supported")
 			return
@@ -84,6 +91,10 @@ func (t *GinTransport) Handler() gin.HandlerFunc {
 		}
 
 		response, err := t.handleMessage(ctx, body)
+		if errors.Is(err, errTransportClosed) {
+			c.String(http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		if err != nil {
 			c.String(http.StatusInternalServerError, err.Error())
 			return
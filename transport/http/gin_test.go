@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+func TestGinContextFromContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tr := NewGinTransport()
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go func() {
+			result := `{"user":""}`
+			if c, ok := GinContextFromContext(ctx); ok {
+				result = `{"user":"` + c.GetString("user") + `"}`
+			}
+			err := tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  json.RawMessage(result),
+			}))
+			if err != nil {
+				t.Errorf("Failed to send response: %v", err)
+			}
+		}()
+	})
+
+	router := gin.New()
+	router.POST("/mcp", func(c *gin.Context) {
+		c.Set("user", "alice")
+	}, tr.Handler())
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response transport.BaseJSONRPCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if string(response.Result) != `{"user":"alice"}` {
+		t.Errorf("Expected the handler to read the user from the gin context, got %s", response.Result)
+	}
+
+	if _, ok := GinContextFromContext(context.Background()); ok {
+		t.Error("Expected no gin context in a background context")
+	}
+}
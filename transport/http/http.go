@@ -3,13 +3,20 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/metoro-io/mcp-golang/transport"
 )
 
+// shutdownTimeout is how long in-flight requests are given to finish when the server is shut down
+const shutdownTimeout = 10 * time.Second
+
 // HTTPTransport implements a stateless HTTP transport for MCP
 type HTTPTransport struct {
 	*baseTransport
@@ -20,25 +27,92 @@ type HTTPTransport struct {
 	closeHandler   func()
 	mu             sync.RWMutex
 	addr           string
+	// Media types accepted for POST bodies, a charset parameter other than utf-8 is always rejected
+	allowedContentTypes []string
+	// Whether a status code reported by the result, e.g. ToolResponse.Status, is used as the HTTP status code
+	toolStatusCodes bool
+	// Whether responses are indented, for debugging
+	indentedJSON bool
+	// Validates the bearer token of every request, requests are unauthenticated if nil
+	jwtValidator func(token string) (transport.Claims, error)
+	// Set by NewHTTPHandlerTransport, Start doesn't listen and requests are only served through ServeHTTP
+	handlerOnly bool
+	// Whether resources/read results without an ETag of their own are sent with a hash of their contents as ETag
+	resourceETags bool
 }
 
 // NewHTTPTransport creates a new HTTP transport that listens on the specified endpoint
 func NewHTTPTransport(endpoint string) *HTTPTransport {
 	return &HTTPTransport{
-		baseTransport: newBaseTransport(),
-		endpoint:      endpoint,
-		addr:          ":8080", // Default port
+		baseTransport:       newBaseTransport(),
+		endpoint:            endpoint,
+		addr:                ":8080", // Default port
+		allowedContentTypes: []string{"application/json"},
 	}
 }
 
+// NewHTTPHandlerTransport creates an HTTP transport that is mounted on an existing server with ServeHTTP,
+// e.g. with mux.Handle("/mcp", transport). Its Start returns immediately without listening.
+func NewHTTPHandlerTransport() *HTTPTransport {
+	t := NewHTTPTransport("")
+	t.handlerOnly = true
+	return t
+}
+
 // WithAddr sets the address to listen on
 func (t *HTTPTransport) WithAddr(addr string) *HTTPTransport {
 	t.addr = addr
 	return t
 }
 
+// WithAllowedContentTypes sets the media types that are accepted for requests, e.g. "application/json".
+// Requests with any other Content-Type are rejected with 415 Unsupported Media Type.
+func (t *HTTPTransport) WithAllowedContentTypes(contentTypes ...string) *HTTPTransport {
+	t.allowedContentTypes = contentTypes
+	return t
+}
+
+// WithToolStatusCodes makes a non-zero ToolResponse.Status the HTTP status code of the response.
+// The JSON-RPC body is sent as usual. Statuses outside of 200-599, and 204 and 304 which can't have a body, are ignored.
+// By default every JSON-RPC response is sent with 200 OK.
+func (t *HTTPTransport) WithToolStatusCodes(enabled bool) *HTTPTransport {
+	t.toolStatusCodes = enabled
+	return t
+}
+
+// WithResourceETags sends resources/read results whose handler set no ResourceResponse.ETag with a hash of the result as ETag,
+// so that clients using HTTPClientTransport.WithResourceCache can revalidate them too. Results with an ETag of their own
+// are always sent with it, and requests with a matching If-None-Match header are answered with 304 Not Modified.
+func (t *HTTPTransport) WithResourceETags(enabled bool) *HTTPTransport {
+	t.resourceETags = enabled
+	return t
+}
+
+// WithIndentedJSON sends responses as indented JSON, to make traffic easier to read while debugging.
+// It must not be used otherwise, as it makes every response bigger. Unlike stdio, HTTP doesn't frame messages by newlines,
+// which is why indenting is offered here and not on the stdio transport.
+func (t *HTTPTransport) WithIndentedJSON(enabled bool) *HTTPTransport {
+	t.indentedJSON = enabled
+	return t
+}
+
+// WithJWTValidator requires every request to carry a bearer token in its Authorization header that validator accepts.
+// Requests without a valid token are rejected with 401 Unauthorized, the claims of valid ones are available to handlers
+// through ClaimsFromContext.
+func (t *HTTPTransport) WithJWTValidator(validator func(token string) (transport.Claims, error)) *HTTPTransport {
+	t.jwtValidator = validator
+	return t
+}
+
 // Start implements Transport.Start
+// It blocks until the server stops. Cancelling ctx shuts the server down, in which case http.ErrServerClosed is returned
+// once requests that are in flight have been answered, or after shutdownTimeout.
+// For a transport created with NewHTTPHandlerTransport it returns immediately, requests are then only served through ServeHTTP.
 func (t *HTTPTransport) Start(ctx context.Context) error {
+	if t.handlerOnly {
+		return nil
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc(t.endpoint, t.handleRequest)
 
@@ -47,35 +121,37 @@ func (t *HTTPTransport) Start(ctx context.Context) error {
 		Handler: mux,
 	}
 
-	return t.server.ListenAndServe()
+	stopped := make(chan struct{})
+	shutDown := make(chan struct{})
+	go func() {
+		defer close(shutDown)
+		select {
+		case <-ctx.Done():
+			// ctx is done already, so in-flight requests get a context of their own to finish in
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := t.server.Shutdown(shutdownCtx); err != nil && t.errorHandler != nil {
+				t.errorHandler(fmt.Errorf("failed to shut down server: %w", err))
+			}
+		case <-stopped:
+		}
+	}()
+
+	err := t.server.ListenAndServe()
+	close(stopped)
+	<-shutDown
+	return err
 }
 
 // Send implements Transport.Send
 func (t *HTTPTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
-	key := message.JsonRpcResponse.Id
-	fmt.Printf("[Send] Attempting to send response with key: %d\n", key)
-
-	responseChannel := t.baseTransport.responseMap[int64(key)]
-	if responseChannel == nil {
-		fmt.Printf("[Send] Response map keys: %v\n", t.getResponseMapKeys())
-
-		return fmt.Errorf("no response channel found for key: %d", key)
-	}
-	responseChannel <- message
-	return nil
-}
-
-// Helper method to get keys
-func (t *HTTPTransport) getResponseMapKeys() []int64 {
-	keys := make([]int64, 0, len(t.baseTransport.responseMap))
-	for k := range t.baseTransport.responseMap {
-		keys = append(keys, k)
-	}
-	return keys
+	return t.baseTransport.Send(ctx, message)
 }
 
 // Close implements Transport.Close
+// Requests that are still waiting for a response are answered with 503 Service Unavailable.
 func (t *HTTPTransport) Close() error {
+	t.releasePending()
 	if t.server != nil {
 		if err := t.server.Close(); err != nil {
 			return err
@@ -109,13 +185,34 @@ func (t *HTTPTransport) SetMessageHandler(handler func(ctx context.Context, mess
 	t.messageHandler = handler
 }
 
+// ServeHTTP implements http.Handler, so that the transport can be mounted on an existing server,
+// e.g. with mux.Handle("/mcp", transport). Create the transport with NewHTTPHandlerTransport so that Start doesn't listen as well.
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.handleRequest(w, r)
+}
+
 func (t *HTTPTransport) handleRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Only POST method is supported", http.StatusMethodNotAllowed)
 		return
 	}
 
-	ctx := r.Context()
+	if err := t.checkContentType(r.Header.Get("Content-Type")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	ctx := transport.ContextWithHTTPHeader(r.Context(), r.Header)
+	if t.jwtValidator != nil {
+		claims, err := t.authorize(r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		ctx = transport.ContextWithClaims(ctx, claims)
+	}
+
 	body, err := t.readBody(r.Body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -123,12 +220,21 @@ func (t *HTTPTransport) handleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response, err := t.handleMessage(ctx, body)
+	if errors.Is(err, errTransportClosed) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	jsonData, err := json.Marshal(response)
+	var jsonData []byte
+	if t.indentedJSON {
+		jsonData, err = json.MarshalIndent(response, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(response)
+	}
 	if err != nil {
 		if t.errorHandler != nil {
 			t.errorHandler(fmt.Errorf("failed to marshal response: %w", err))
@@ -137,6 +243,67 @@ func (t *HTTPTransport) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Resource contents are sent with an ETag, so that clients caching them only download them again once they change
+	if etag := t.responseETag(response); etag != "" {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	if t.toolStatusCodes && response.Type == transport.BaseMessageTypeJSONRPCResponseType && hasBody(response.JsonRpcResponse.StatusCode) {
+		w.WriteHeader(response.JsonRpcResponse.StatusCode)
+	}
 	w.Write(jsonData)
 }
+
+// responseETag returns the ETag header a response is sent with, or "" if it has none
+func (t *HTTPTransport) responseETag(response *transport.BaseJsonRpcMessage) string {
+	if response.Type != transport.BaseMessageTypeJSONRPCResponseType || response.JsonRpcResponse.ETag == nil {
+		return ""
+	}
+	if etag := *response.JsonRpcResponse.ETag; etag != "" {
+		return quoteETag(etag)
+	}
+	if t.resourceETags {
+		return resourceETag(response.JsonRpcResponse.Result)
+	}
+	return ""
+}
+
+// hasBody reports whether code is a valid HTTP status code that a response with a body can be sent with
+func hasBody(code int) bool {
+	return code >= 200 && code <= 599 && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// authorize validates the bearer token of an Authorization header and returns its claims
+func (t *HTTPTransport) authorize(authorization string) (transport.Claims, error) {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	claims, err := t.jwtValidator(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return claims, nil
+}
+
+// checkContentType returns an error if the Content-Type header is not one of the allowed media types
+func (t *HTTPTransport) checkContentType(contentType string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return fmt.Errorf("unsupported charset %q", charset)
+	}
+	for _, allowed := range t.allowedContentTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported content type %q", mediaType)
+}
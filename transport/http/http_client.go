@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/metoro-io/mcp-golang/transport"
 )
@@ -26,6 +29,19 @@ type HTTPClientTransport struct {
 	mu             sync.RWMutex
 	client         HTTPClient
 	headers        map[string]string
+	// Called on every Send for headers that change per request, they take precedence over headers
+	headerFunc func(ctx context.Context) map[string]string
+	// How many times a request that failed with a connection error is sent again, and how long to wait in between
+	maxRetries   int
+	retryBackoff time.Duration
+	// Set by WithResourceCache
+	resourceCache *resourceCache
+	// Whether error responses whose error is a string are accepted
+	lenientErrors bool
+	// Whether the client was set with WithClient, in which case WithTransportConfig leaves it alone
+	customClient bool
+	// Whether requests are indented, for debugging
+	indentedJSON bool
 }
 
 // NewHTTPClientTransport creates a new HTTP client transport that connects to the specified endpoint
@@ -40,6 +56,21 @@ func NewHTTPClientTransport(endpoint string) *HTTPClientTransport {
 // WithClient allows to set a custom HTTP client
 func (t *HTTPClientTransport) WithClient(c HTTPClient) *HTTPClientTransport {
 	t.client = c
+	t.customClient = true
+	return t
+}
+
+// WithTransportConfig tunes connection reuse for callers sending many requests: at most maxIdle idle connections are kept
+// in total and maxIdlePerHost per host, each closed after idleTimeout. It has no effect on a client set with WithClient.
+func (t *HTTPClientTransport) WithTransportConfig(maxIdle, maxIdlePerHost int, idleTimeout time.Duration) *HTTPClientTransport {
+	if t.customClient {
+		return t
+	}
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.MaxIdleConns = maxIdle
+	httpTransport.MaxIdleConnsPerHost = maxIdlePerHost
+	httpTransport.IdleConnTimeout = idleTimeout
+	t.client = &http.Client{Transport: httpTransport}
 	return t
 }
 
@@ -55,6 +86,44 @@ func (t *HTTPClientTransport) WithHeader(key, value string) *HTTPClientTransport
 	return t
 }
 
+// WithHeaderFunc sets a function that returns headers for each request, e.g. a freshly refreshed auth token.
+// It is called on every Send with the context of the request, and its headers replace headers set with WithHeader.
+func (t *HTTPClientTransport) WithHeaderFunc(headerFunc func(ctx context.Context) map[string]string) *HTTPClientTransport {
+	t.headerFunc = headerFunc
+	return t
+}
+
+// WithRetry sends a request again, up to maxRetries times and waiting backoff in between, when it fails because
+// the connection was refused or reset, e.g. while the server restarts. Requests that got an HTTP response are never retried.
+func (t *HTTPClientTransport) WithRetry(maxRetries int, backoff time.Duration) *HTTPClientTransport {
+	t.maxRetries = maxRetries
+	t.retryBackoff = backoff
+	return t
+}
+
+// WithResourceCache caches up to size resources/read results by uri, along with the ETag the server sent them with.
+// Reading a cached uri again sends If-None-Match, and the cached result is used when the server answers 304 Not Modified.
+// Cached results are dropped after ttl, a ttl of zero keeps them until they are evicted. Only results sent with an ETag are
+// cached, i.e. those whose handler set ResourceResponse.ETag, or all of them if the server uses HTTPTransport.WithResourceETags.
+func (t *HTTPClientTransport) WithResourceCache(size int, ttl time.Duration) *HTTPClientTransport {
+	t.resourceCache = newResourceCache(size, ttl)
+	return t
+}
+
+// WithLenientErrors accepts error responses whose error is a plain string rather than an error object, as sent by some
+// non-conformant servers. They are read with transport.UnmarshalLenientError.
+func (t *HTTPClientTransport) WithLenientErrors(enabled bool) *HTTPClientTransport {
+	t.lenientErrors = enabled
+	return t
+}
+
+// WithIndentedJSON sends requests as indented JSON, to make traffic easier to read while debugging.
+// It must not be used otherwise, as it makes every request bigger.
+func (t *HTTPClientTransport) WithIndentedJSON(enabled bool) *HTTPClientTransport {
+	t.indentedJSON = enabled
+	return t
+}
+
 // Start implements Transport.Start
 func (t *HTTPClientTransport) Start(ctx context.Context) error {
 	// Does nothing in the stateless http client transport
@@ -63,24 +132,30 @@ func (t *HTTPClientTransport) Start(ctx context.Context) error {
 
 // Send implements Transport.Send
 func (t *HTTPClientTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
-	jsonData, err := json.Marshal(message)
+	var jsonData []byte
+	var err error
+	if t.indentedJSON {
+		jsonData, err = json.MarshalIndent(message, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(message)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	url := fmt.Sprintf("%s%s", t.baseURL, t.endpoint)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	for key, value := range t.headers {
-		req.Header.Set(key, value)
+	var header map[string]string
+	var cached *resourceCacheEntry
+	uri, isResourceRead := resourceReadURI(message)
+	if t.resourceCache != nil && isResourceRead {
+		if entry, ok := t.resourceCache.get(uri); ok {
+			cached = entry
+			header = map[string]string{"If-None-Match": entry.etag}
+		}
 	}
 
-	resp, err := t.client.Do(req)
+	resp, err := t.post(ctx, jsonData, header)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -89,67 +164,155 @@ func (t *HTTPClientTransport) Send(ctx context.Context, message *transport.BaseJ
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		t.resourceCache.revalidated(uri)
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
+
+		if handler != nil {
+			handler(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  cached.result,
+			}))
+		}
+		return nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("server returned error: %s (status: %d)", string(body), resp.StatusCode)
 	}
 
-	if len(body) > 0 {
-		// Try to unmarshal as a response first
+	if etag := resp.Header.Get("ETag"); t.resourceCache != nil && isResourceRead && etag != "" {
 		var response transport.BaseJSONRPCResponse
 		if err := json.Unmarshal(body, &response); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
+			t.resourceCache.put(uri, etag, response.Result)
+		}
+	}
 
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageResponse(&response))
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	// Servers can send notifications along with the response as a JSON-RPC batch, see transport.NotificationBufferTransport
+	if body[0] == '[' {
+		var messages []json.RawMessage
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return fmt.Errorf("received invalid response: %s", string(body))
+		}
+		for _, message := range messages {
+			if err := t.handleMessage(ctx, message); err != nil {
+				return err
 			}
-			return nil
 		}
+		return nil
+	}
+	return t.handleMessage(ctx, body)
+}
 
-		// Try as an error
-		var errorResponse transport.BaseJSONRPCError
-		if err := json.Unmarshal(body, &errorResponse); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
+// handleMessage passes a single JSON-RPC message received from the server to the message handler
+func (t *HTTPClientTransport) handleMessage(ctx context.Context, body []byte) error {
+	// Try to unmarshal as a response first
+	var response transport.BaseJSONRPCResponse
+	if err := json.Unmarshal(body, &response); err == nil {
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
 
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageError(&errorResponse))
-			}
-			return nil
+		if handler != nil {
+			handler(ctx, transport.NewBaseMessageResponse(&response))
 		}
+		return nil
+	}
 
-		// Try as a notification
-		var notification transport.BaseJSONRPCNotification
-		if err := json.Unmarshal(body, &notification); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
+	// Try as a notification, before an error as those don't have required fields
+	var notification transport.BaseJSONRPCNotification
+	if err := json.Unmarshal(body, &notification); err == nil {
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
 
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageNotification(&notification))
-			}
-			return nil
+		if handler != nil {
+			handler(ctx, transport.NewBaseMessageNotification(&notification))
 		}
+		return nil
+	}
 
-		// Try as a request
-		var request transport.BaseJSONRPCRequest
-		if err := json.Unmarshal(body, &request); err == nil {
-			t.mu.RLock()
-			handler := t.messageHandler
-			t.mu.RUnlock()
+	// Try as an error
+	var errorResponse transport.BaseJSONRPCError
+	unmarshalError := func(data []byte, m *transport.BaseJSONRPCError) error { return json.Unmarshal(data, m) }
+	if t.lenientErrors {
+		unmarshalError = transport.UnmarshalLenientError
+	}
+	if err := unmarshalError(body, &errorResponse); err == nil {
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
 
-			if handler != nil {
-				handler(ctx, transport.NewBaseMessageRequest(&request))
-			}
-			return nil
+		if handler != nil {
+			handler(ctx, transport.NewBaseMessageError(&errorResponse))
 		}
+		return nil
+	}
+
+	// Try as a request
+	var request transport.BaseJSONRPCRequest
+	if err := json.Unmarshal(body, &request); err == nil {
+		t.mu.RLock()
+		handler := t.messageHandler
+		t.mu.RUnlock()
 
-		return fmt.Errorf("received invalid response: %s", string(body))
+		if handler != nil {
+			handler(ctx, transport.NewBaseMessageRequest(&request))
+		}
+		return nil
 	}
 
-	return nil
+	return fmt.Errorf("received invalid response: %s", string(body))
+}
+
+// post sends the body to the endpoint with the given extra headers, retrying on connection errors if WithRetry is set
+func (t *HTTPClientTransport) post(ctx context.Context, body []byte, header map[string]string) (*http.Response, error) {
+	url := fmt.Sprintf("%s%s", t.baseURL, t.endpoint)
+	var requestHeaders map[string]string
+	if t.headerFunc != nil {
+		requestHeaders = t.headerFunc(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range t.headers {
+			req.Header.Set(key, value)
+		}
+		for key, value := range requestHeaders {
+			req.Header.Set(key, value)
+		}
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := t.client.Do(req)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= t.maxRetries || !isConnectionError(err) {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		select {
+		case <-time.After(t.retryBackoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+	}
+}
+
+// isConnectionError reports whether the request failed because the connection to the server was refused or reset
+func isConnectionError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
 }
 
 // Close implements Transport.Close
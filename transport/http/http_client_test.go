@@ -0,0 +1,309 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// flakyClient fails the first failures requests with err, then answers with a JSON-RPC response
+type flakyClient struct {
+	failures int
+	err      error
+	bodies   []string
+}
+
+func (c *flakyClient) Do(r *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.bodies = append(c.bodies, string(body))
+	if len(c.bodies) <= c.failures {
+		return nil, c.err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":{}}`)),
+	}, nil
+}
+
+func TestHTTPClientTransport_Retry(t *testing.T) {
+	connectionRefused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name          string
+		maxRetries    int
+		err           error
+		expectedCalls int
+		expectError   bool
+	}{
+		{name: "retries connection refused", maxRetries: 2, err: connectionRefused, expectedCalls: 2},
+		{name: "no retries by default", maxRetries: 0, err: connectionRefused, expectedCalls: 1, expectError: true},
+		{name: "other errors are not retried", maxRetries: 2, err: errors.New("tls: bad certificate"), expectedCalls: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &flakyClient{failures: 1, err: tt.err}
+			tr := NewHTTPClientTransport("/mcp").WithClient(client).WithRetry(tt.maxRetries, 0)
+			var received *transport.BaseJsonRpcMessage
+			tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				received = message
+			})
+
+			err := tr.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+				Jsonrpc: "2.0",
+				Id:      1,
+				Method:  "ping",
+			}))
+
+			if len(client.bodies) != tt.expectedCalls {
+				t.Fatalf("Expected %d requests, got %d", tt.expectedCalls, len(client.bodies))
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			// The retried request has the same body
+			if client.bodies[1] != client.bodies[0] {
+				t.Errorf("Expected the same body to be sent again, got %s and %s", client.bodies[0], client.bodies[1])
+			}
+			if received == nil || received.Type != transport.BaseMessageTypeJSONRPCResponseType {
+				t.Errorf("Expected the response to be handled, got %v", received)
+			}
+		})
+	}
+}
+
+// headerRecordingClient records the headers of every request and answers with a JSON-RPC response
+type headerRecordingClient struct {
+	headers []http.Header
+}
+
+func (c *headerRecordingClient) Do(r *http.Request) (*http.Response, error) {
+	c.headers = append(c.headers, r.Header.Clone())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":{}}`)),
+	}, nil
+}
+
+func TestHTTPClientTransport_HeaderFunc(t *testing.T) {
+	type tokenKey struct{}
+	client := &headerRecordingClient{}
+	tr := NewHTTPClientTransport("/mcp").
+		WithClient(client).
+		WithHeader("Authorization", "Bearer static").
+		WithHeader("X-Client", "test").
+		WithHeaderFunc(func(ctx context.Context) map[string]string {
+			token, _ := ctx.Value(tokenKey{}).(string)
+			return map[string]string{"Authorization": "Bearer " + token}
+		})
+
+	for _, token := range []string{"first", "second"} {
+		ctx := context.WithValue(context.Background(), tokenKey{}, token)
+		err := tr.Send(ctx, transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Id:      1,
+			Method:  "ping",
+		}))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+	}
+
+	if len(client.headers) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(client.headers))
+	}
+	for i, token := range []string{"first", "second"} {
+		if got := client.headers[i].Get("Authorization"); got != "Bearer "+token {
+			t.Errorf("Expected Authorization %q on request %d, got %q", "Bearer "+token, i, got)
+		}
+		if got := client.headers[i].Get("X-Client"); got != "test" {
+			t.Errorf("Expected static header X-Client on request %d, got %q", i, got)
+		}
+	}
+}
+
+// statusRecordingClient sends requests with http.DefaultClient and records the status of every response
+type statusRecordingClient struct {
+	statuses []int
+}
+
+func (c *statusRecordingClient) Do(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(r)
+	if err == nil {
+		c.statuses = append(c.statuses, resp.StatusCode)
+	}
+	return resp, err
+}
+
+func TestHTTPClientTransport_ResourceCache(t *testing.T) {
+	contents := `{"contents":[{"uri":"file:///readme.md","text":"# readme"}],"etag":"v1"}`
+	etag := "v1"
+	serverTransport := NewHTTPTransport("/mcp")
+	serverTransport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go serverTransport.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(contents),
+			ETag:    &etag,
+		}))
+	})
+	server := httptest.NewServer(http.HandlerFunc(serverTransport.handleRequest))
+	defer server.Close()
+
+	client := &statusRecordingClient{}
+	tr := NewHTTPClientTransport("/mcp").WithBaseURL(server.URL).WithClient(client).WithResourceCache(10, time.Minute)
+	var received []*transport.BaseJSONRPCResponse
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		received = append(received, message.JsonRpcResponse)
+	})
+
+	for id := 1; id <= 2; id++ {
+		err := tr.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Id:      transport.RequestId(id),
+			Method:  "resources/read",
+			Params:  json.RawMessage(`{"uri":"file:///readme.md"}`),
+		}))
+		if err != nil {
+			t.Fatalf("Failed to read resource: %v", err)
+		}
+	}
+
+	// The second read is answered with 304 and served from the cache
+	if len(client.statuses) != 2 || client.statuses[0] != http.StatusOK || client.statuses[1] != http.StatusNotModified {
+		t.Fatalf("Expected statuses 200 and 304, got %v", client.statuses)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(received))
+	}
+	for i, response := range received {
+		if response.Id != transport.RequestId(i+1) {
+			t.Errorf("Expected response id %d, got %d", i+1, response.Id)
+		}
+		if string(response.Result) != contents {
+			t.Errorf("Expected contents %s, got %s", contents, response.Result)
+		}
+	}
+}
+
+// staticClient answers every request with the same body
+type staticClient struct {
+	body string
+}
+
+func (c *staticClient) Do(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(c.body)),
+	}, nil
+}
+
+func TestHTTPClientTransport_LenientErrors(t *testing.T) {
+	client := &staticClient{body: `{"jsonrpc":"2.0","id":1,"error":"database unavailable"}`}
+	request := transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Jsonrpc: "2.0",
+		Id:      1,
+		Method:  "tools/call",
+	})
+
+	// A string error is rejected by default
+	err := NewHTTPClientTransport("/mcp").WithClient(client).Send(context.Background(), request)
+	if err == nil {
+		t.Fatal("Expected a string error to be rejected")
+	}
+
+	tr := NewHTTPClientTransport("/mcp").WithClient(client).WithLenientErrors(true)
+	var received *transport.BaseJsonRpcMessage
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		received = message
+	})
+	if err := tr.Send(context.Background(), request); err != nil {
+		t.Fatalf("Expected the string error to be accepted, got %v", err)
+	}
+	if received == nil || received.Type != transport.BaseMessageTypeJSONRPCErrorType {
+		t.Fatalf("Expected an error message, got %+v", received)
+	}
+	if received.JsonRpcError.Id != 1 || received.JsonRpcError.Error.Code != transport.LenientErrorCode || received.JsonRpcError.Error.Message != "database unavailable" {
+		t.Errorf("Unexpected error %+v", received.JsonRpcError)
+	}
+}
+
+func TestHTTPClientTransport_TransportConfig(t *testing.T) {
+	tr := NewHTTPClientTransport("/mcp").WithTransportConfig(50, 10, time.Minute)
+	httpTransport := tr.client.(*http.Client).Transport.(*http.Transport)
+	if httpTransport.MaxIdleConns != 50 || httpTransport.MaxIdleConnsPerHost != 10 || httpTransport.IdleConnTimeout != time.Minute {
+		t.Errorf("Unexpected transport config: %d idle, %d per host, %s timeout", httpTransport.MaxIdleConns, httpTransport.MaxIdleConnsPerHost, httpTransport.IdleConnTimeout)
+	}
+
+	// A custom client is kept as is
+	custom := &headerRecordingClient{}
+	tr = NewHTTPClientTransport("/mcp").WithClient(custom).WithTransportConfig(50, 10, time.Minute)
+	if tr.client != custom {
+		t.Error("Expected the custom client to be kept")
+	}
+}
+
+// BenchmarkHTTPClientTransport_ConnectionReuse reports how many connections are opened per request.
+// With idle connections kept per host, sequential requests reuse a single connection.
+func BenchmarkHTTPClientTransport_ConnectionReuse(b *testing.B) {
+	for _, bm := range []struct {
+		name           string
+		maxIdlePerHost int
+	}{
+		{name: "no idle connections", maxIdlePerHost: -1},
+		{name: "tuned", maxIdlePerHost: 10},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			var mu sync.Mutex
+			connections := 0
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+			}))
+			server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					mu.Lock()
+					connections++
+					mu.Unlock()
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			tr := NewHTTPClientTransport("/mcp").WithBaseURL(server.URL).WithTransportConfig(100, bm.maxIdlePerHost, time.Minute)
+			request := transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+				Jsonrpc: "2.0",
+				Id:      1,
+				Method:  "ping",
+			})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := tr.Send(context.Background(), request); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			mu.Lock()
+			b.ReportMetric(float64(connections)/float64(b.N), "conns/op")
+			mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,602 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// newJSONRequest returns a POST request to /mcp with a JSON body
+func newJSONRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestHTTPTransport_StartCancelledByContext(t *testing.T) {
+	tr := NewHTTPTransport("/mcp").WithAddr("localhost:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tr.Start(ctx)
+	}()
+
+	// Give the server a moment to begin listening
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != http.ErrServerClosed {
+			t.Fatalf("Expected http.ErrServerClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not return after the context was cancelled")
+	}
+}
+
+func TestHTTPTransport_StartDrainsRequestsOnCancel(t *testing.T) {
+	// Reserve a free port, so that the test knows where to send its request
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	tr := NewHTTPTransport("/mcp").WithAddr(addr)
+	received := make(chan struct{})
+	release := make(chan struct{})
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go func() {
+			close(received)
+			<-release
+			tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  json.RawMessage(`{}`),
+			}))
+		}()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tr.Start(ctx)
+	}()
+
+	statusCh := make(chan int, 1)
+	go func() {
+		for i := 0; i < 100; i++ {
+			resp, err := http.Post("http://"+addr+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			resp.Body.Close()
+			statusCh <- resp.StatusCode
+			return
+		}
+		statusCh <- 0
+	}()
+
+	// Cancel while the request is in flight, it is still answered
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("The request never reached the transport")
+	}
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-errCh:
+		t.Fatalf("Start returned before the in-flight request was answered: %v", err)
+	default:
+	}
+	close(release)
+
+	if status := <-statusCh; status != http.StatusOK {
+		t.Errorf("Expected the in-flight request to be answered with 200, got %d", status)
+	}
+	select {
+	case err := <-errCh:
+		if err != http.ErrServerClosed {
+			t.Fatalf("Expected http.ErrServerClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not return after the in-flight request was answered")
+	}
+}
+
+func TestHTTPTransport_SendNotification(t *testing.T) {
+	tr := NewHTTPTransport("/mcp")
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go func() {
+			// A notification sent while handling the request must not break the response
+			err := tr.Send(ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+				Jsonrpc: "2.0",
+				Method:  "notifications/message",
+			}))
+			if err != nil {
+				t.Errorf("Expected notification to be accepted, got %v", err)
+			}
+			err = tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  json.RawMessage(`{}`),
+			}))
+			if err != nil {
+				t.Errorf("Failed to send response: %v", err)
+			}
+		}()
+	})
+
+	body := `{"jsonrpc":"2.0","id":7,"method":"ping"}`
+	w := httptest.NewRecorder()
+	tr.handleRequest(w, newJSONRequest(body))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response transport.BaseJSONRPCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Id != 7 {
+		t.Errorf("Expected response id 7, got %d", response.Id)
+	}
+
+	// A response without a pending request is still an error
+	err := tr.Send(context.Background(), transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+		Jsonrpc: "2.0",
+		Id:      42,
+		Result:  json.RawMessage(`{}`),
+	}))
+	if err == nil {
+		t.Error("Expected error when sending a response without a pending request")
+	}
+}
+
+func TestHTTPTransport_CloseReleasesPendingRequests(t *testing.T) {
+	tr := NewHTTPTransport("/mcp")
+	received := make(chan struct{})
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		// Never respond
+		close(received)
+	})
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`
+		tr.handleRequest(w, newJSONRequest(body))
+	}()
+
+	<-received
+	if err := tr.Close(); err != nil {
+		t.Fatalf("Failed to close transport: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Pending request was not released after Close")
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+
+	// Requests after Close are rejected straight away
+	w = httptest.NewRecorder()
+	tr.handleRequest(w, newJSONRequest(`{"jsonrpc":"2.0","id":2,"method":"ping"}`))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 after close, got %d", w.Code)
+	}
+}
+
+func TestHTTPTransport_ContentType(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		allowed      []string
+		expectedCode int
+	}{
+		{name: "json", contentType: "application/json", expectedCode: http.StatusOK},
+		{name: "json with utf-8 charset", contentType: "application/json; charset=UTF-8", expectedCode: http.StatusOK},
+		{name: "json with other charset", contentType: "application/json; charset=latin1", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "form encoded", contentType: "application/x-www-form-urlencoded", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "missing", contentType: "", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "custom allowlist", contentType: "application/vnd.mcp+json", allowed: []string{"application/json", "application/vnd.mcp+json"}, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewHTTPTransport("/mcp")
+			if tt.allowed != nil {
+				tr.WithAllowedContentTypes(tt.allowed...)
+			}
+			tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+					Jsonrpc: "2.0",
+					Id:      message.JsonRpcRequest.Id,
+					Result:  json.RawMessage(`{}`),
+				}))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			tr.handleRequest(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestHTTPTransport_ToolStatusCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		enabled      bool
+		statusCode   int
+		expectedCode int
+	}{
+		{name: "disabled by default", enabled: false, statusCode: http.StatusNotFound, expectedCode: http.StatusOK},
+		{name: "enabled", enabled: true, statusCode: http.StatusNotFound, expectedCode: http.StatusNotFound},
+		{name: "invalid status", enabled: true, statusCode: 1000, expectedCode: http.StatusOK},
+		{name: "informational status", enabled: true, statusCode: http.StatusContinue, expectedCode: http.StatusOK},
+		{name: "status without a body", enabled: true, statusCode: http.StatusNoContent, expectedCode: http.StatusOK},
+		{name: "not modified", enabled: true, statusCode: http.StatusNotModified, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewHTTPTransport("/mcp").WithToolStatusCodes(tt.enabled)
+			tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+					Jsonrpc:    "2.0",
+					Id:         message.JsonRpcRequest.Id,
+					Result:     json.RawMessage(`{"content":[],"isError":false}`),
+					StatusCode: tt.statusCode,
+				}))
+			})
+
+			w := httptest.NewRecorder()
+			tr.handleRequest(w, newJSONRequest(`{"jsonrpc":"2.0","id":3,"method":"tools/call"}`))
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			// The JSON-RPC body is sent either way
+			var response transport.BaseJSONRPCResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Id != 3 {
+				t.Errorf("Expected response id 3, got %d", response.Id)
+			}
+		})
+	}
+}
+
+func TestHTTPTransport_OriginalRequestID(t *testing.T) {
+	tr := NewHTTPTransport("/mcp")
+	var contextID transport.RequestId
+	var found bool
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		contextID, found = transport.RequestIDFromContext(ctx)
+		go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{}`),
+		}))
+	})
+
+	w := httptest.NewRecorder()
+	tr.handleRequest(w, newJSONRequest(`{"jsonrpc":"2.0","id":42,"method":"ping"}`))
+
+	// The message carries the transport's internal key, the context the id the client sent
+	if !found || contextID != 42 {
+		t.Errorf("Expected request id 42 in the context, got %d (found %t)", contextID, found)
+	}
+	var response transport.BaseJSONRPCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Id != 42 {
+		t.Errorf("Expected response id 42, got %d", response.Id)
+	}
+}
+
+func TestHTTPTransport_NotificationBuffer(t *testing.T) {
+	serverTransport := NewHTTPTransport("/mcp")
+	buffered := transport.NewNotificationBufferTransport(serverTransport)
+	buffered.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go func() {
+			// Progress of the call, sent before its result
+			for _, progress := range []string{"1", "2"} {
+				buffered.Send(ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+					Jsonrpc: "2.0",
+					Method:  "notifications/progress",
+					Params:  json.RawMessage(`{"progressToken":"call","progress":` + progress + `}`),
+				}))
+			}
+			buffered.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  json.RawMessage(`{"content":[]}`),
+			}))
+		}()
+	})
+	server := httptest.NewServer(http.HandlerFunc(serverTransport.handleRequest))
+	defer server.Close()
+
+	client := NewHTTPClientTransport("/mcp").WithBaseURL(server.URL)
+	var received []*transport.BaseJsonRpcMessage
+	client.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		received = append(received, message)
+	})
+	for id := 7; id <= 8; id++ {
+		err := client.Send(context.Background(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+			Jsonrpc: "2.0",
+			Id:      transport.RequestId(id),
+			Method:  "tools/call",
+		}))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+	}
+
+	// Every response arrives after the notifications that were sent before it, with the client's id
+	if len(received) != 6 {
+		t.Fatalf("Expected 6 messages, got %d", len(received))
+	}
+	for i, message := range received {
+		if i%3 == 2 {
+			if message.Type != transport.BaseMessageTypeJSONRPCResponseType {
+				t.Fatalf("Expected message %d to be a response, got %s", i, message.Type)
+			}
+			if expected := transport.RequestId(7 + i/3); message.JsonRpcResponse.Id != expected {
+				t.Errorf("Expected response id %d, got %d", expected, message.JsonRpcResponse.Id)
+			}
+			continue
+		}
+		if message.Type != transport.BaseMessageTypeJSONRPCNotificationType {
+			t.Fatalf("Expected message %d to be a notification, got %s", i, message.Type)
+		}
+		if expected := `{"progressToken":"call","progress":` + string(rune('1'+i%3)) + `}`; string(message.JsonRpcNotification.Params) != expected {
+			t.Errorf("Expected notification params %s, got %s", expected, message.JsonRpcNotification.Params)
+		}
+	}
+}
+
+func TestHTTPTransport_IndentedJSON(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		tr := NewHTTPTransport("/mcp").WithIndentedJSON(enabled)
+		tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  json.RawMessage(`{"tools":[]}`),
+			}))
+		})
+
+		w := httptest.NewRecorder()
+		tr.handleRequest(w, newJSONRequest(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+
+		expected := `{"id":1,"jsonrpc":"2.0","result":{"tools":[]}}`
+		if enabled {
+			expected = "{\n  \"id\": 1,\n  \"jsonrpc\": \"2.0\",\n  \"result\": {\n    \"tools\": []\n  }\n}"
+		}
+		if w.Body.String() != expected {
+			t.Errorf("Expected body %q with indenting %t, got %q", expected, enabled, w.Body.String())
+		}
+	}
+}
+
+func TestHTTPTransport_JWTValidator(t *testing.T) {
+	tr := NewHTTPTransport("/mcp").WithJWTValidator(func(token string) (transport.Claims, error) {
+		if token != "valid" {
+			return nil, errors.New("signature mismatch")
+		}
+		return transport.Claims{"sub": "user-1"}, nil
+	})
+	var claims transport.Claims
+	var found bool
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		claims, found = transport.ClaimsFromContext(ctx)
+		go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{}`),
+		}))
+	})
+
+	for _, authorization := range []string{"", "Basic dXNlcjpwYXNz", "Bearer invalid"} {
+		req := newJSONRequest(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		tr.handleRequest(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for Authorization %q, got %d", authorization, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("Expected a Bearer challenge for Authorization %q, got %q", authorization, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+	if found {
+		t.Fatal("Expected unauthorized requests not to reach the handler")
+	}
+
+	req := newJSONRequest(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	req.Header.Set("Authorization", "Bearer valid")
+	w := httptest.NewRecorder()
+	tr.handleRequest(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !found || claims["sub"] != "user-1" {
+		t.Errorf("Expected the token's claims in the context, got %v (found %t)", claims, found)
+	}
+}
+
+func TestHTTPTransport_NotificationAfterResponse(t *testing.T) {
+	tr := NewHTTPTransport("/mcp")
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go tr.Send(ctx, transport.NewBaseMessageBatch(
+			transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      message.JsonRpcRequest.Id,
+				Result:  json.RawMessage(`{}`),
+			}),
+			transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+				Jsonrpc: "2.0",
+				Method:  "notifications/done",
+			}),
+		))
+	})
+
+	w := httptest.NewRecorder()
+	tr.handleRequest(w, newJSONRequest(`{"jsonrpc":"2.0","id":7,"method":"tools/call"}`))
+
+	expected := `[{"id":7,"jsonrpc":"2.0","result":{}},{"jsonrpc":"2.0","method":"notifications/done"}]`
+	if w.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, w.Body.String())
+	}
+}
+
+func TestHTTPTransport_HeaderInContext(t *testing.T) {
+	tr := NewHTTPTransport("/mcp")
+	var header http.Header
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		header, _ = transport.HTTPHeaderFromContext(ctx)
+		go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{}`),
+		}))
+	})
+
+	req := newJSONRequest(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+	req.Header.Set("Accept-Language", "fr-CH, fr;q=0.9")
+	tr.handleRequest(httptest.NewRecorder(), req)
+
+	if header.Get("Accept-Language") != "fr-CH, fr;q=0.9" {
+		t.Errorf("Expected the request headers in the context, got %v", header)
+	}
+}
+
+func TestHTTPTransport_ServeHTTPOnMux(t *testing.T) {
+	tr := NewHTTPHandlerTransport()
+	tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      message.JsonRpcRequest.Id,
+			Result:  json.RawMessage(`{}`),
+		}))
+	})
+
+	// Start doesn't listen, the application's own server serves the transport
+	if err := tr.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/mcp", tr)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var response transport.BaseJSONRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Id != 7 {
+		t.Errorf("Expected response id 7, got %d", response.Id)
+	}
+
+	health, err := http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	health.Body.Close()
+	if health.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected the other routes of the mux to be served, got status %d", health.StatusCode)
+	}
+}
+
+func TestHTTPTransport_ResourceETags(t *testing.T) {
+	result := json.RawMessage(`{"contents":[{"uri":"file:///readme.md","text":"# readme"}]}`)
+	tests := []struct {
+		name          string
+		etag          *string
+		resourceETags bool
+		expected      string
+	}{
+		{name: "etag of the handler", etag: stringPointer("v1"), expected: `"v1"`},
+		{name: "weak etag of the handler", etag: stringPointer(`W/"v1"`), expected: `W/"v1"`},
+		{name: "no etag by default", etag: stringPointer(""), expected: ""},
+		{name: "hashed when enabled", etag: stringPointer(""), resourceETags: true, expected: resourceETag(result)},
+		{name: "results that can't be cached", etag: nil, resourceETags: true, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewHTTPTransport("/mcp").WithResourceETags(tt.resourceETags)
+			tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+				go tr.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+					Jsonrpc: "2.0",
+					Id:      message.JsonRpcRequest.Id,
+					Result:  result,
+					ETag:    tt.etag,
+				}))
+			})
+
+			recorder := httptest.NewRecorder()
+			tr.handleRequest(recorder, newJSONRequest(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"file:///readme.md"}}`))
+			if etag := recorder.Header().Get("ETag"); etag != tt.expected {
+				t.Fatalf("Expected ETag %q, got %q", tt.expected, etag)
+			}
+			if tt.expected == "" {
+				return
+			}
+
+			// A request with a matching If-None-Match header is answered without a body
+			req := newJSONRequest(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"file:///readme.md"}}`)
+			req.Header.Set("If-None-Match", tt.expected)
+			recorder = httptest.NewRecorder()
+			tr.handleRequest(recorder, req)
+			if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+				t.Errorf("Expected 304 without a body, got %d %q", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// resourceETag returns the ETag the HTTPTransport sends for a resources/read result without one of its own, see WithResourceETags
+func resourceETag(result json.RawMessage) string {
+	sum := sha256.Sum256(result)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// quoteETag turns the ETag of a ResourceResponse into an HTTP entity tag, which is quoted and may be marked as weak
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// resourceReadURI returns the uri read by a resources/read request, or false for any other message
+func resourceReadURI(message *transport.BaseJsonRpcMessage) (string, bool) {
+	if message.Type != transport.BaseMessageTypeJSONRPCRequestType || message.JsonRpcRequest.Method != "resources/read" {
+		return "", false
+	}
+	var params struct {
+		Uri string `json:"uri"`
+	}
+	if err := json.Unmarshal(message.JsonRpcRequest.Params, &params); err != nil || params.Uri == "" {
+		return "", false
+	}
+	return params.Uri, true
+}
+
+// resourceCacheEntry is a resources/read result and the ETag the server sent it with
+type resourceCacheEntry struct {
+	uri    string
+	etag   string
+	result json.RawMessage
+	stored time.Time
+}
+
+// resourceCache is a least recently used cache of resources/read results, keyed by uri
+type resourceCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newResourceCache(size int, ttl time.Duration) *resourceCache {
+	return &resourceCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached result for uri, unless it has expired
+func (c *resourceCache) get(uri string) (*resourceCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[uri]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*resourceCacheEntry)
+	if c.ttl > 0 && time.Since(entry.stored) > c.ttl {
+		c.order.Remove(element)
+		delete(c.entries, uri)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+// put caches result for uri, evicting the least recently used result if the cache is full
+func (c *resourceCache) put(uri string, etag string, result json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &resourceCacheEntry{uri: uri, etag: etag, result: result, stored: time.Now()}
+	if element, ok := c.entries[uri]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[uri] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resourceCacheEntry).uri)
+	}
+}
+
+// revalidated restarts the ttl of the result for uri after the server confirmed it is unchanged
+func (c *resourceCache) revalidated(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[uri]; ok {
+		element.Value.(*resourceCacheEntry).stored = time.Now()
+	}
+}
@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"context"
+	"sync"
+)
+
+// NotificationBufferTransport wraps a request/response transport, such as the stateless HTTP transport, that has no way to
+// send notifications to the client outside of a response. Notifications are held back and sent together with the next
+// response or error, as a JSON-RPC batch, so that clients still receive e.g. progress and log notifications of a tool call.
+//
+// Ordering: buffered notifications are sent in the order they were sent, followed by the response they are flushed with.
+// A notification is flushed with the first response sent after it. With concurrent requests that may be the response
+// to a different request than the one that caused it, and notifications sent after the last response stay buffered
+// until another response is sent.
+type NotificationBufferTransport struct {
+	Transport
+	mu      sync.Mutex
+	pending []*BaseJsonRpcMessage
+}
+
+// NewNotificationBufferTransport wraps t so that its notifications are sent along with its responses
+func NewNotificationBufferTransport(t Transport) *NotificationBufferTransport {
+	return &NotificationBufferTransport{Transport: t}
+}
+
+// BatchesWithResponse implements ResponseBatchTransport
+func (t *NotificationBufferTransport) BatchesWithResponse() bool {
+	return true
+}
+
+// Send implements Transport.Send
+func (t *NotificationBufferTransport) Send(ctx context.Context, message *BaseJsonRpcMessage) error {
+	switch message.Type {
+	case BaseMessageTypeJSONRPCNotificationType:
+		t.mu.Lock()
+		t.pending = append(t.pending, message)
+		t.mu.Unlock()
+		return nil
+	case BaseMessageTypeJSONRPCResponseType, BaseMessageTypeJSONRPCErrorType, BaseMessageTypeJSONRPCBatchType:
+		t.mu.Lock()
+		pending := t.pending
+		t.pending = nil
+		t.mu.Unlock()
+		if len(pending) == 0 {
+			return t.Transport.Send(ctx, message)
+		}
+		if message.Type == BaseMessageTypeJSONRPCBatchType {
+			return t.Transport.Send(ctx, NewBaseMessageBatch(append(pending, message.JsonRpcBatch...)...))
+		}
+		return t.Transport.Send(ctx, NewBaseMessageBatch(append(pending, message)...))
+	default:
+		return t.Transport.Send(ctx, message)
+	}
+}
@@ -0,0 +1,59 @@
+package stdio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// StdioServerParameters describes how to start an MCP server that is run as a subprocess and talked to over stdio,
+// in the format of an entry of the mcpServers config used by MCP clients
+type StdioServerParameters struct {
+	// The executable to run.
+	Command string `json:"command" yaml:"command" mapstructure:"command"`
+
+	// Arguments passed to the command.
+	Args []string `json:"args,omitempty" yaml:"args,omitempty" mapstructure:"args,omitempty"`
+
+	// Environment variables set for the command, in addition to the environment of the current process.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty" mapstructure:"env,omitempty"`
+}
+
+// Cmd returns the command that starts the server. Connect a client to it with NewStdioServerTransportWithIO,
+// reading from the command's stdout and writing to its stdin.
+func (p StdioServerParameters) Cmd(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	if len(p.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range p.Env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	return cmd
+}
+
+// LoadStdioServers reads the servers of an MCP config file of the form {"mcpServers": {"name": {"command": ..., "args": [...], "env": {...}}}},
+// keyed by name
+func LoadStdioServers(path string) (map[string]StdioServerParameters, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MCP config %s: %w", path, err)
+	}
+	var config struct {
+		McpServers map[string]StdioServerParameters `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse MCP config %s: %w", path, err)
+	}
+	for name, server := range config.McpServers {
+		if server.Command == "" {
+			return nil, fmt.Errorf("MCP server %q in %s has no command", name, path)
+		}
+	}
+	if config.McpServers == nil {
+		config.McpServers = make(map[string]StdioServerParameters)
+	}
+	return config.McpServers, nil
+}
@@ -0,0 +1,49 @@
+package stdio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadStdioServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	config := `{
+  "mcpServers": {
+    "weather": {
+      "command": "weather-server",
+      "args": ["--units", "metric"],
+      "env": {"WEATHER_API_KEY": "secret"}
+    },
+    "files": {
+      "command": "npx",
+      "args": ["-y", "@modelcontextprotocol/server-filesystem", "/tmp"]
+    }
+  }
+}`
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o644))
+
+	servers, err := LoadStdioServers(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StdioServerParameters{
+		"weather": {Command: "weather-server", Args: []string{"--units", "metric"}, Env: map[string]string{"WEATHER_API_KEY": "secret"}},
+		"files":   {Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-filesystem", "/tmp"}},
+	}, servers)
+
+	cmd := servers["weather"].Cmd(context.Background())
+	assert.Equal(t, []string{"weather-server", "--units", "metric"}, cmd.Args)
+	assert.Contains(t, cmd.Env, "WEATHER_API_KEY=secret")
+	// Without env the command inherits the current environment
+	assert.Nil(t, servers["files"].Cmd(context.Background()).Env)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"mcpServers":{"broken":{"args":["x"]}}}`), 0o644))
+	_, err = LoadStdioServers(path)
+	assert.ErrorContains(t, err, `MCP server "broken"`)
+
+	_, err = LoadStdioServers(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -68,6 +68,8 @@ import (
 type ReadBuffer struct {
 	mu     sync.Mutex
 	buffer []byte
+	// Lenient accepts error responses whose error is a string, see transport.UnmarshalLenientError
+	Lenient bool
 }
 
 // NewReadBuffer creates a new ReadBuffer.
@@ -104,7 +106,7 @@ func (rb *ReadBuffer) ReadMessage() (*transport.BaseJsonRpcMessage, error) {
 			line := string(rb.buffer[:i])
 			//println("read line: ", line)
 			rb.buffer = rb.buffer[i+1:]
-			return deserializeMessage(line)
+			return deserializeMessage(line, rb.Lenient)
 		}
 	}
 
@@ -119,7 +121,7 @@ func (rb *ReadBuffer) Clear() {
 }
 
 // deserializeMessage deserializes a JSON-RPC message from a string.
-func deserializeMessage(line string) (*transport.BaseJsonRpcMessage, error) {
+func deserializeMessage(line string, lenient bool) (*transport.BaseJsonRpcMessage, error) {
 	var request transport.BaseJSONRPCRequest
 	if err := json.Unmarshal([]byte(line), &request); err == nil {
 		//println("unmarshaled request:", spew.Sdump(request))
@@ -143,7 +145,11 @@ func deserializeMessage(line string) (*transport.BaseJsonRpcMessage, error) {
 	}
 
 	var errorResponse transport.BaseJSONRPCError
-	if err := json.Unmarshal([]byte(line), &errorResponse); err == nil {
+	unmarshalError := func(data []byte, m *transport.BaseJSONRPCError) error { return json.Unmarshal(data, m) }
+	if lenient {
+		unmarshalError = transport.UnmarshalLenientError
+	}
+	if err := unmarshalError([]byte(line), &errorResponse); err == nil {
 		return transport.NewBaseMessageError(&errorResponse), nil
 	} else {
 		//println("unmarshaled error response error:", err.Error())
@@ -98,7 +98,7 @@ func TestMessageDeserialization(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msg, err := deserializeMessage(tt.input)
+			msg, err := deserializeMessage(tt.input, false)
 			if err != nil {
 				t.Errorf("deserializeMessage failed: %v", err)
 			}
@@ -112,7 +112,7 @@ func TestMessageDeserialization(t *testing.T) {
 	}
 
 	t.Run("request", func(t *testing.T) {
-		msg, err := deserializeMessage(`{"jsonrpc":"2.0","id":1,"method":"test","params":{}}`)
+		msg, err := deserializeMessage(`{"jsonrpc":"2.0","id":1,"method":"test","params":{}}`, false)
 		assert.NoError(t, err)
 		assert.Equal(t, transport.BaseMessageTypeJSONRPCRequestType, msg.Type)
 		assert.Equal(t, "2.0", msg.JsonRpcRequest.Jsonrpc)
@@ -121,7 +121,7 @@ func TestMessageDeserialization(t *testing.T) {
 	})
 
 	t.Run("notification", func(t *testing.T) {
-		msg, err := deserializeMessage(`{"jsonrpc":"2.0","method":"test","params":{}}`)
+		msg, err := deserializeMessage(`{"jsonrpc":"2.0","method":"test","params":{}}`, false)
 		assert.NoError(t, err)
 		assert.Equal(t, transport.BaseMessageTypeJSONRPCNotificationType, msg.Type)
 		assert.Equal(t, "2.0", msg.JsonRpcNotification.Jsonrpc)
@@ -129,11 +129,24 @@ func TestMessageDeserialization(t *testing.T) {
 	})
 
 	t.Run("error", func(t *testing.T) {
-		msg, err := deserializeMessage(`{"jsonrpc":"2.0","id":1,"error":{"code":-32700,"message":"Parse error"}}`)
+		msg, err := deserializeMessage(`{"jsonrpc":"2.0","id":1,"error":{"code":-32700,"message":"Parse error"}}`, false)
 		assert.NoError(t, err)
 		assert.Equal(t, transport.BaseMessageTypeJSONRPCErrorType, msg.Type)
 		assert.Equal(t, "2.0", msg.JsonRpcError.Jsonrpc)
 		assert.Equal(t, -32700, msg.JsonRpcError.Error.Code)
 		assert.Equal(t, "Parse error", msg.JsonRpcError.Error.Message)
 	})
+
+	t.Run("string error", func(t *testing.T) {
+		line := `{"jsonrpc":"2.0","id":4,"error":"database unavailable"}`
+		_, err := deserializeMessage(line, false)
+		assert.Error(t, err)
+
+		msg, err := deserializeMessage(line, true)
+		assert.NoError(t, err)
+		assert.Equal(t, transport.BaseMessageTypeJSONRPCErrorType, msg.Type)
+		assert.Equal(t, transport.RequestId(4), msg.JsonRpcError.Id)
+		assert.Equal(t, transport.LenientErrorCode, msg.JsonRpcError.Error.Code)
+		assert.Equal(t, "database unavailable", msg.JsonRpcError.Error.Message)
+	})
 }
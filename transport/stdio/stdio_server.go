@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/metoro-io/mcp-golang/transport/stdio/internal/stdio"
@@ -21,8 +22,16 @@ type StdioServerTransport struct {
 	writer    io.Writer
 	readBuf   *stdio.ReadBuffer
 	onClose   func()
+	closeOnce sync.Once
 	onError   func(error)
 	onMessage func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+
+	// Cancelled by Close, see ConnectionContext
+	connCtx    context.Context
+	cancelConn context.CancelFunc
 }
 
 // NewStdioServerTransport creates a new StdioServerTransport using os.Stdin and os.Stdout
@@ -32,13 +41,31 @@ func NewStdioServerTransport() *StdioServerTransport {
 
 // NewStdioServerTransportWithIO creates a new StdioServerTransport with custom io.Reader and io.Writer
 func NewStdioServerTransportWithIO(in io.Reader, out io.Writer) *StdioServerTransport {
+	connCtx, cancelConn := context.WithCancel(context.Background())
 	return &StdioServerTransport{
-		reader:  bufio.NewReader(in),
-		writer:  out,
-		readBuf: stdio.NewReadBuffer(),
+		reader:     bufio.NewReader(in),
+		writer:     out,
+		readBuf:    stdio.NewReadBuffer(),
+		connCtx:    connCtx,
+		cancelConn: cancelConn,
 	}
 }
 
+// WithIdleTimeout closes the transport, firing the close handler, when no input has been received for the given duration.
+// This lets a server started as a subprocess exit when its client abandons it without closing the pipe.
+// A zero duration, the default, disables the timeout.
+func (t *StdioServerTransport) WithIdleTimeout(timeout time.Duration) *StdioServerTransport {
+	t.idleTimeout = timeout
+	return t
+}
+
+// WithLenientErrors accepts error responses whose error is a plain string rather than an error object, as sent by some
+// non-conformant servers. They are read with transport.UnmarshalLenientError.
+func (t *StdioServerTransport) WithLenientErrors(enabled bool) *StdioServerTransport {
+	t.readBuf.Lenient = enabled
+	return t
+}
+
 // Start begins listening for messages on stdin
 func (t *StdioServerTransport) Start(ctx context.Context) error {
 	t.mu.Lock()
@@ -47,22 +74,42 @@ func (t *StdioServerTransport) Start(ctx context.Context) error {
 		return fmt.Errorf("StdioServerTransport already started")
 	}
 	t.started = true
+	if t.idleTimeout > 0 {
+		t.idleTimer = time.AfterFunc(t.idleTimeout, func() {
+			t.Close()
+		})
+	}
 	t.mu.Unlock()
 
 	go t.readLoop(ctx)
 	return nil
 }
 
-// Close stops the transport and cleans up resources
+// ConnectionContext implements transport.ConnectionTransport.
+// The context is cancelled when the transport is closed, including when the input is closed by the client.
+func (t *StdioServerTransport) ConnectionContext() context.Context {
+	return t.connCtx
+}
+
+// Close stops the transport and cleans up resources.
+// The close handler is called once, after the transport is unlocked, however often the transport is closed.
 func (t *StdioServerTransport) Close() error {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	t.started = false
-	t.readBuf.Clear()
-	if t.onClose != nil {
-		t.onClose()
+	t.cancelConn()
+	if t.idleTimer != nil {
+		t.idleTimer.Stop()
+		t.idleTimer = nil
 	}
+	t.readBuf.Clear()
+	onClose := t.onClose
+	t.mu.Unlock()
+
+	t.closeOnce.Do(func() {
+		if onClose != nil {
+			onClose()
+		}
+	})
 	return nil
 }
 
@@ -124,9 +171,22 @@ func (t *StdioServerTransport) readLoop(ctx context.Context) {
 				if err != io.EOF {
 					t.handleError(fmt.Errorf("read error: %w", err))
 				}
+				// The client closed its end or the input broke, nothing more can be received
+				t.mu.Lock()
+				started := t.started
+				t.mu.Unlock()
+				if started {
+					t.Close()
+				}
 				return
 			}
 
+			t.mu.Lock()
+			if t.idleTimer != nil {
+				t.idleTimer.Reset(t.idleTimeout)
+			}
+			t.mu.Unlock()
+
 			t.readBuf.Append(buffer[:n])
 			t.processReadBuffer()
 		}
@@ -165,7 +225,7 @@ func (t *StdioServerTransport) handleMessage(msg *transport.BaseJsonRpcMessage)
 	handler := t.onMessage
 	t.mu.Unlock()
 
-	ctx := context.Background()
+	ctx := transport.ContextWithConnection(t.connCtx, t.connCtx)
 
 	if handler != nil {
 		handler(ctx, msg)
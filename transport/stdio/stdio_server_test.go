@@ -3,7 +3,10 @@ package stdio
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -164,4 +167,153 @@ func TestStdioServerTransport(t *testing.T) {
 
 		assert.True(t, closed, "transport should be closed after context cancellation")
 	})
+
+	t.Run("input closed", func(t *testing.T) {
+		in, inWriter := io.Pipe()
+		out := &bytes.Buffer{}
+		transport := NewStdioServerTransportWithIO(in, out)
+
+		var closeCalls atomic.Int32
+		closed := make(chan struct{})
+		transport.SetCloseHandler(func() {
+			if closeCalls.Add(1) == 1 {
+				close(closed)
+			}
+		})
+
+		err := transport.Start(context.Background())
+		assert.NoError(t, err)
+
+		// The client closing stdin ends the session
+		assert.NoError(t, inWriter.Close())
+		select {
+		case <-closed:
+			// Success
+		case <-time.After(time.Second):
+			t.Fatal("close handler was not called when the input was closed")
+		}
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(1), closeCalls.Load())
+
+		// Closing it explicitly as well doesn't call the handler again
+		assert.NoError(t, transport.Close())
+		assert.Equal(t, int32(1), closeCalls.Load())
+	})
+
+	t.Run("close handler uses the transport", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tr := NewStdioServerTransportWithIO(&bytes.Buffer{}, out)
+
+		// The handler is called after the transport is unlocked, so it can still use it
+		tr.SetCloseHandler(func() {
+			tr.Send(context.Background(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+				Jsonrpc: "2.0",
+				Method:  "closed",
+			}))
+		})
+		done := make(chan struct{})
+		go func() {
+			tr.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Close deadlocked calling the close handler")
+		}
+		assert.Contains(t, out.String(), `"method":"closed"`)
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		in, inWriter := io.Pipe()
+		out := &bytes.Buffer{}
+		transport := NewStdioServerTransportWithIO(in, out)
+
+		readErrors := make(chan error, 1)
+		transport.SetErrorHandler(func(err error) {
+			readErrors <- err
+		})
+		closed := make(chan struct{})
+		transport.SetCloseHandler(func() {
+			close(closed)
+		})
+
+		err := transport.Start(context.Background())
+		assert.NoError(t, err)
+
+		assert.NoError(t, inWriter.CloseWithError(errors.New("broken pipe")))
+		select {
+		case <-closed:
+			// Success
+		case <-time.After(time.Second):
+			t.Fatal("close handler was not called after a read error")
+		}
+		assert.ErrorContains(t, <-readErrors, "broken pipe")
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		in, inWriter := io.Pipe()
+		defer inWriter.Close()
+		out := &bytes.Buffer{}
+		transport := NewStdioServerTransportWithIO(in, out).WithIdleTimeout(100 * time.Millisecond)
+
+		closed := make(chan struct{})
+		transport.SetCloseHandler(func() {
+			close(closed)
+		})
+
+		err := transport.Start(context.Background())
+		assert.NoError(t, err)
+
+		// Input received before the timeout keeps the transport open
+		time.Sleep(60 * time.Millisecond)
+		_, err = inWriter.Write([]byte(`{"jsonrpc": "2.0", "method": "test"}` + "\n"))
+		assert.NoError(t, err)
+		time.Sleep(60 * time.Millisecond)
+		select {
+		case <-closed:
+			t.Fatal("transport closed even though input was received")
+		default:
+		}
+
+		select {
+		case <-closed:
+			// Success
+		case <-time.After(time.Second):
+			t.Fatal("close handler was not called after the idle timeout")
+		}
+	})
+
+	t.Run("connection context", func(t *testing.T) {
+		in, inWriter := io.Pipe()
+		defer inWriter.Close()
+		out := &bytes.Buffer{}
+		tr := NewStdioServerTransportWithIO(in, out)
+
+		handlerCtx := make(chan context.Context, 1)
+		tr.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+			handlerCtx <- ctx
+		})
+
+		err := tr.Start(context.Background())
+		assert.NoError(t, err)
+
+		_, err = inWriter.Write([]byte(`{"jsonrpc": "2.0", "method": "test"}` + "\n"))
+		assert.NoError(t, err)
+		var ctx context.Context
+		select {
+		case ctx = <-handlerCtx:
+		case <-time.After(time.Second):
+			t.Fatal("message was not handled")
+		}
+		connection, ok := transport.ConnectionFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, tr.ConnectionContext(), connection)
+		assert.NoError(t, tr.ConnectionContext().Err())
+
+		// Closing the transport cancels the connection and the contexts of messages received over it
+		assert.NoError(t, tr.Close())
+		assert.ErrorIs(t, tr.ConnectionContext().Err(), context.Canceled)
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
 }
@@ -32,3 +32,22 @@ type Transport interface {
 	// Partially deserializes the messages to pass a BaseJsonRpcMessage
 	SetMessageHandler(handler func(ctx context.Context, message *BaseJsonRpcMessage))
 }
+
+// ConnectionTransport is implemented by transports that keep a connection to a single client open, such as stdio.
+type ConnectionTransport interface {
+	Transport
+
+	// ConnectionContext returns a context that is cancelled once the connection is closed, by Close or by the client
+	// disconnecting. Unlike the context of a request, it outlives the requests received over the connection, so it can
+	// be used to stop background work started for the client. It is the parent of the contexts messages are handled with.
+	ConnectionContext() context.Context
+}
+
+// ResponseBatchTransport is implemented by request/response transports, such as the stateless HTTP transport,
+// that can only send messages to the client as part of a response.
+type ResponseBatchTransport interface {
+	Transport
+
+	// BatchesWithResponse reports whether messages to send right after a response should be sent in one batch with it
+	BatchesWithResponse() bool
+}
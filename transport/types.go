@@ -34,6 +34,30 @@ type BaseJSONRPCError struct {
 	Jsonrpc string `json:"jsonrpc" yaml:"jsonrpc" mapstructure:"jsonrpc"`
 }
 
+// LenientErrorCode is the code of errors that were sent as a plain string, see UnmarshalLenientError
+const LenientErrorCode = -32603
+
+// UnmarshalLenientError unmarshals an error response like json.Unmarshal, but also accepts the non-conformant
+// "error": "message" that some servers send instead of an error object. Such an error is read as its message with LenientErrorCode.
+func UnmarshalLenientError(data []byte, m *BaseJSONRPCError) error {
+	var lenient struct {
+		Error   json.RawMessage `json:"error"`
+		Id      RequestId       `json:"id"`
+		Jsonrpc string          `json:"jsonrpc"`
+	}
+	if err := json.Unmarshal(data, &lenient); err != nil {
+		return err
+	}
+	var message string
+	if err := json.Unmarshal(lenient.Error, &message); err != nil {
+		return json.Unmarshal(data, m)
+	}
+	m.Error = BaseJSONRPCErrorInner{Code: LenientErrorCode, Message: message}
+	m.Id = lenient.Id
+	m.Jsonrpc = lenient.Jsonrpc
+	return nil
+}
+
 type BaseJSONRPCRequest struct {
 	// Id corresponds to the JSON schema field "id".
 	Id RequestId `json:"id" yaml:"id" mapstructure:"id"`
@@ -98,9 +122,10 @@ type BaseJSONRPCNotification struct {
 // Requires a Jsonrpc and Method
 func (m *BaseJSONRPCNotification) UnmarshalJSON(data []byte) error {
 	required := struct {
-		Jsonrpc *string `json:"jsonrpc" yaml:"jsonrpc" mapstructure:"jsonrpc"`
-		Method  *string `json:"method" yaml:"method" mapstructure:"method"`
-		Id      *int64  `json:"id" yaml:"id" mapstructure:"id"`
+		Jsonrpc *string          `json:"jsonrpc" yaml:"jsonrpc" mapstructure:"jsonrpc"`
+		Method  *string          `json:"method" yaml:"method" mapstructure:"method"`
+		Id      *int64           `json:"id" yaml:"id" mapstructure:"id"`
+		Params  *json.RawMessage `json:"params" yaml:"params" mapstructure:"params"`
 	}{}
 	err := json.Unmarshal(data, &required)
 	if err != nil {
@@ -117,6 +142,9 @@ func (m *BaseJSONRPCNotification) UnmarshalJSON(data []byte) error {
 	}
 	m.Jsonrpc = *required.Jsonrpc
 	m.Method = *required.Method
+	if required.Params != nil {
+		m.Params = *required.Params
+	}
 	return nil
 }
 
@@ -131,6 +159,25 @@ type BaseJSONRPCResponse struct {
 
 	// Result corresponds to the JSON schema field "result".
 	Result json.RawMessage `json:"result" yaml:"result" mapstructure:"result"`
+
+	// StatusCode is a transport level status reported by the result, e.g. ToolResponse.Status.
+	// It is not part of the JSON-RPC message and is only used by transports that opt in to it.
+	StatusCode int `json:"-" yaml:"-" mapstructure:"-"`
+
+	// ETag is set for results that transports may cache, e.g. resources/read results, to the entity tag the result
+	// reports, or to "" if it reports none. It is not part of the JSON-RPC message.
+	ETag *string `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// StatusCoder is implemented by results that carry a transport level status code
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// ETagger is implemented by results that transports may cache.
+// ETag returns the entity tag of the result, or "" if it has none, and whether this result can be cached at all.
+type ETagger interface {
+	ETag() (string, bool)
 }
 
 // Custom Response unmarshaling
@@ -168,6 +215,8 @@ const (
 	BaseMessageTypeJSONRPCNotificationType BaseMessageType = "notification"
 	BaseMessageTypeJSONRPCResponseType     BaseMessageType = "response"
 	BaseMessageTypeJSONRPCErrorType        BaseMessageType = "error"
+	// Several messages sent together as a JSON array, see NewBaseMessageBatch
+	BaseMessageTypeJSONRPCBatchType BaseMessageType = "batch"
 )
 
 type BaseJsonRpcMessage struct {
@@ -176,6 +225,7 @@ type BaseJsonRpcMessage struct {
 	JsonRpcNotification *BaseJSONRPCNotification
 	JsonRpcResponse     *BaseJSONRPCResponse
 	JsonRpcError        *BaseJSONRPCError
+	JsonRpcBatch        []*BaseJsonRpcMessage
 }
 
 func (m *BaseJsonRpcMessage) MarshalJSON() ([]byte, error) {
@@ -188,6 +238,8 @@ func (m *BaseJsonRpcMessage) MarshalJSON() ([]byte, error) {
 		return json.Marshal(m.JsonRpcResponse)
 	case BaseMessageTypeJSONRPCErrorType:
 		return json.Marshal(m.JsonRpcError)
+	case BaseMessageTypeJSONRPCBatchType:
+		return json.Marshal(m.JsonRpcBatch)
 	default:
 		return nil, errors.New("unknown message type, couldn't marshal")
 	}
@@ -224,3 +276,11 @@ func NewBaseMessageError(error *BaseJSONRPCError) *BaseJsonRpcMessage {
 		},
 	}
 }
+
+// NewBaseMessageBatch creates a message that sends messages together, in order, as a JSON-RPC batch
+func NewBaseMessageBatch(messages ...*BaseJsonRpcMessage) *BaseJsonRpcMessage {
+	return &BaseJsonRpcMessage{
+		Type:         BaseMessageTypeJSONRPCBatchType,
+		JsonRpcBatch: messages,
+	}
+}